@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+)
+
+// Quiet stretch that counts as a pause between phrases
+const minPauseMs = 300
+
+// chunkBoundaries moves chunk ends onto pauses in speech. Each recorded
+// segment is joined to the audio carried over from the previous one and cut
+// at the latest pause between minSecs and maxSecs; the audio after the cut
+// is carried into the next chunk.
+type chunkBoundaries struct {
+	minSecs     int
+	maxSecs     int
+	thresholdDB float64
+	carry       []int16
+	carryStart  float64 // timeline offset where the carried audio starts
+	sampleRate  int
+	channels    int
+	lastNum     int
+}
+
+// newChunkBoundaries returns nil when the config asks for fixed-length chunks
+func newChunkBoundaries(config *Config) *chunkBoundaries {
+	minSecs, maxSecs := config.ChunkMinSecs, config.ChunkMaxSecs
+	if minSecs <= 0 {
+		minSecs = config.ChunkDurationInSecs
+	}
+	if maxSecs <= 0 {
+		maxSecs = config.ChunkDurationInSecs
+	}
+	if minSecs == maxSecs {
+		return nil
+	}
+	return &chunkBoundaries{minSecs: minSecs, maxSecs: maxSecs, thresholdDB: config.VADThresholdDB}
+}
+
+// apply rewrites the chunk's audio file in place to end at a pause and sets
+// the chunk's actual start and end times. It is a no-op on nil.
+func (b *chunkBoundaries) apply(chunk *AudioChunk) error {
+	if b == nil {
+		return nil
+	}
+	b.lastNum = chunk.Num
+
+	audio, err := readWAV(chunk.Path)
+	if err != nil {
+		// The carried audio can't be joined to this chunk, so it is lost
+		b.carry = nil
+		return fmt.Errorf("failed to read chunk audio for boundary detection: %v", err)
+	}
+
+	start := chunk.Start
+	samples := audio.Samples
+	if len(b.carry) > 0 {
+		start = b.carryStart
+		samples = append(b.carry, samples...)
+	}
+	b.sampleRate, b.channels = audio.SampleRate, audio.channelCount()
+
+	cut := b.findCut(samples, audio.SampleRate, b.channels)
+	if err := writeWAV(chunk.Path, &wavAudio{SampleRate: audio.SampleRate, Channels: audio.Channels, Samples: samples[:cut]}); err != nil {
+		return fmt.Errorf("failed to write chunk audio: %v", err)
+	}
+
+	b.carry = append([]int16(nil), samples[cut:]...)
+	b.carryStart = start + float64(cut)/float64(audio.samplesPerSec())
+	chunk.Start = start
+	chunk.End = b.carryStart
+	return nil
+}
+
+// flush writes any carried audio as one last chunk, numbered after the last
+// one, to the file pattern gives for that number
+func (b *chunkBoundaries) flush(pattern string) (AudioChunk, bool, error) {
+	if b == nil || len(b.carry) == 0 {
+		return AudioChunk{}, false, nil
+	}
+
+	chunk := AudioChunk{
+		Num:   b.lastNum + 1,
+		Path:  fmt.Sprintf(pattern, b.lastNum+1),
+		Start: b.carryStart,
+		End:   b.carryStart + float64(len(b.carry))/float64(b.sampleRate*b.channels),
+	}
+	err := writeWAV(chunk.Path, &wavAudio{SampleRate: b.sampleRate, Channels: b.channels, Samples: b.carry})
+	b.carry = nil
+	if err != nil {
+		return AudioChunk{}, false, fmt.Errorf("failed to write final chunk audio: %v", err)
+	}
+	return chunk, true, nil
+}
+
+// findCut returns the sample index to end the chunk at: the middle of the
+// latest pause between minSecs and maxSecs, or maxSecs if there is none.
+// Audio shorter than minSecs is taken whole. At most maxSecs is left to carry
+// into the next chunk, so the carry cannot keep growing when pauses fall
+// just after minSecs. Samples are interleaved across channels, and the cut
+// always falls between whole frames.
+func (b *chunkBoundaries) findCut(samples []int16, sampleRate, channels int) int {
+	maxLen := b.maxSecs * sampleRate * channels
+	lo := b.minSecs * sampleRate * channels
+	if rest := len(samples) - maxLen; rest > lo {
+		lo = rest
+	}
+	hi := maxLen
+	if hi > len(samples) {
+		hi = len(samples)
+	}
+	if hi <= lo {
+		return hi
+	}
+
+	frameLen := sampleRate * vadFrameMs / 1000 * channels
+	minPause := minPauseMs / vadFrameMs
+	cut := hi
+	runStart, runLen := 0, 0
+	for i := lo; i+frameLen <= hi; i += frameLen {
+		if frameLevelDB(samples[i:i+frameLen]) < b.thresholdDB {
+			if runLen == 0 {
+				runStart = i
+			}
+			runLen++
+			if runLen >= minPause {
+				cut = runStart + runLen/2*frameLen
+			}
+			continue
+		}
+		runLen = 0
+	}
+	return cut
+}
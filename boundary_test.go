@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// speechWithPauses returns secs of loud 1 kHz mono audio starting at from on
+// the timeline, silent wherever quiet reports a pause
+func speechWithPauses(from, secs float64, quiet func(t float64) bool) *wavAudio {
+	const rate = 1000
+	audio := &wavAudio{SampleRate: rate, Samples: make([]int16, int(secs*rate))}
+	for i := range audio.Samples {
+		if quiet(from + float64(i)/rate) {
+			continue
+		}
+		audio.Samples[i] = 8000
+		if i%2 == 1 {
+			audio.Samples[i] = -8000
+		}
+	}
+	return audio
+}
+
+func TestChunkBoundariesCapCarry(t *testing.T) {
+	const minSecs, maxSecs, segmentSecs = 20, 30, 30
+
+	// The only pause in reach always falls just after minSecs, so every
+	// chunk is cut short and the rest of the segment is carried
+	quiet := func(t float64) bool {
+		k := math.Floor(t / 20.5)
+		return k > 0 && t-k*20.5 < 0.4
+	}
+
+	b := &chunkBoundaries{minSecs: minSecs, maxSecs: maxSecs, thresholdDB: -45}
+	dir := t.TempDir()
+	pattern := filepath.Join(dir, "chunk_%d.wav")
+	end := 0.0
+	for num := 1; num <= 12; num++ {
+		recorded := float64(num-1) * segmentSecs
+		chunk := AudioChunk{Num: num, Path: fmt.Sprintf(pattern, num), Start: recorded, End: recorded + segmentSecs}
+		if err := writeWAV(chunk.Path, speechWithPauses(recorded, segmentSecs, quiet)); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.apply(&chunk); err != nil {
+			t.Fatalf("apply chunk %d: %v", num, err)
+		}
+
+		if chunk.Start != end {
+			t.Errorf("chunk %d starts at %v, want %v where the last one ended", num, chunk.Start, end)
+		}
+		if length := chunk.End - chunk.Start; length < minSecs || length > maxSecs {
+			t.Errorf("chunk %d is %vs long, want %d-%ds", num, length, minSecs, maxSecs)
+		}
+		if carried := float64(len(b.carry)) / 1000; carried > maxSecs {
+			t.Errorf("chunk %d carries %vs, want at most %ds", num, carried, maxSecs)
+		}
+		if lag := recorded + segmentSecs - chunk.End; lag > maxSecs {
+			t.Errorf("chunk %d ends %vs behind the recording, want at most %ds", num, lag, maxSecs)
+		}
+		end = chunk.End
+	}
+
+	last, ok, err := b.flush(pattern)
+	if err != nil || !ok {
+		t.Fatalf("flush = %v, %v", ok, err)
+	}
+	if last.Start != end || last.End != 12*segmentSecs {
+		t.Errorf("final chunk spans %v-%v, want %v-%d", last.Start, last.End, end, 12*segmentSecs)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"sync"
+)
+
+// ChunkEvent is published for every chunk appended to a session's
+// transcript, and once more with Type "end" when the session finishes.
+// Offsets are seconds on the session timeline.
+type ChunkEvent struct {
+	Type      string              `json:"type"` // "chunk" or "end"
+	SessionID string              `json:"session_id"`
+	Chunk     int                 `json:"chunk,omitempty"`
+	Start     float64             `json:"start"`
+	End       float64             `json:"end"`
+	Text      string              `json:"text,omitempty"`
+	Segments  []TranscriptSegment `json:"segments,omitempty"`
+}
+
+// Subscribers that fall this far behind are dropped rather than stalling
+// transcription
+const subscriberBufferSize = 64
+
+// Broadcaster fans chunk events out to live subscribers and keeps each
+// session's history so late joiners can replay it
+type Broadcaster struct {
+	mu          sync.Mutex
+	history     map[string][]ChunkEvent
+	finished    map[string]bool
+	subscribers map[string]map[chan ChunkEvent]struct{}
+}
+
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{
+		history:     make(map[string][]ChunkEvent),
+		finished:    make(map[string]bool),
+		subscribers: make(map[string]map[chan ChunkEvent]struct{}),
+	}
+}
+
+// Publish records event in its session's history and delivers it to the
+// session's subscribers. It is a no-op on a nil Broadcaster.
+func (b *Broadcaster) Publish(event ChunkEvent) {
+	if b == nil {
+		return
+	}
+	if event.Type == "" {
+		event.Type = "chunk"
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.history[event.SessionID] = append(b.history[event.SessionID], event)
+	for ch := range b.subscribers[event.SessionID] {
+		select {
+		case ch <- event:
+		default:
+			// Too slow; drop the subscriber so it can reconnect and replay
+			delete(b.subscribers[event.SessionID], ch)
+			close(ch)
+		}
+	}
+}
+
+// Finish publishes the end event for a session and closes its subscribers
+func (b *Broadcaster) Finish(sessionID string) {
+	if b == nil {
+		return
+	}
+
+	b.Publish(ChunkEvent{Type: "end", SessionID: sessionID})
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.finished[sessionID] = true
+	for ch := range b.subscribers[sessionID] {
+		close(ch)
+	}
+	delete(b.subscribers, sessionID)
+}
+
+// Subscribe returns the session's events so far and a channel for the ones
+// that follow. The channel is closed when the session finishes or the
+// subscriber falls behind. Call cancel to unsubscribe.
+func (b *Broadcaster) Subscribe(sessionID string) (replay []ChunkEvent, events <-chan ChunkEvent, cancel func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	replay = append([]ChunkEvent(nil), b.history[sessionID]...)
+	ch := make(chan ChunkEvent, subscriberBufferSize)
+	if b.finished[sessionID] {
+		close(ch)
+		return replay, ch, func() {}
+	}
+
+	if b.subscribers[sessionID] == nil {
+		b.subscribers[sessionID] = make(map[chan ChunkEvent]struct{})
+	}
+	b.subscribers[sessionID][ch] = struct{}{}
+
+	cancel = func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[sessionID][ch]; ok {
+			delete(b.subscribers[sessionID], ch)
+			close(ch)
+		}
+	}
+	return replay, ch, cancel
+}
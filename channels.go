@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// channelName returns the speaker label for channel c of a dual recording
+func (t *Transcriber) channelName(c int) string {
+	if c < len(t.config.ChannelNames) {
+		return t.config.ChannelNames[c]
+	}
+	return fmt.Sprintf("Channel %d", c+1)
+}
+
+// transcribeChannels transcribes each channel of a multi-channel chunk on its
+// own and merges the segments by start time, labeled with the channel's name
+func (t *Transcriber) transcribeChannels(chunk AudioChunk, audio *wavAudio, outputPath, prompt string) (*TranscriptionResult, error) {
+	merged := &TranscriptionResult{}
+	base := strings.TrimSuffix(chunk.Path, filepath.Ext(chunk.Path))
+
+	for c := 0; c < audio.channelCount(); c++ {
+		name := t.channelName(c)
+		mono := audio.channel(c)
+		if t.isSilentAudio(mono, chunk.Start-chunk.WindowStart) {
+			fmt.Printf("Skipping silent channel %q of chunk %d\n", name, chunk.Num)
+			continue
+		}
+
+		path := fmt.Sprintf("%s_ch%d.wav", base, c+1)
+		if err := writeWAV(path, mono); err != nil {
+			return nil, fmt.Errorf("failed to write channel %q of chunk %d: %v", name, chunk.Num, err)
+		}
+		result, err := t.whisperService.Transcribe(path, fmt.Sprintf("%s_ch%d", outputPath, c+1), prompt)
+		os.Remove(path)
+		if err != nil {
+			return nil, fmt.Errorf("transcription failed for chunk %d channel %q: %v", chunk.Num, name, err)
+		}
+
+		if merged.Language == "" {
+			merged.Language = result.Language
+		}
+		for _, seg := range result.Segments {
+			seg.Speaker = name
+			merged.Segments = append(merged.Segments, seg)
+		}
+	}
+
+	sort.SliceStable(merged.Segments, func(i, j int) bool {
+		return merged.Segments[i].Start < merged.Segments[j].Start
+	})
+	return merged, nil
+}
@@ -10,6 +10,7 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // Version is set at build time via ldflags
@@ -19,6 +20,12 @@ func printUsage() {
 	fmt.Printf("Usage: %s <command> [options]\n\n", os.Args[0])
 	fmt.Println("Commands:")
 	fmt.Println("  run       Run transcribe mode - record and transcribe immediately")
+	fmt.Println("  process   Transcribe existing audio files from a file or directory")
+	fmt.Println("  retry     Re-run failed or pending chunks of a session: retry <session>")
+	fmt.Println("  postprocess  Apply the configured text_filters to an existing transcript: postprocess <file>")
+	fmt.Println("  reveal    Show the values redacted from a transcript: reveal <transcript>")
+	fmt.Println("  serve     Run an HTTP API server for live sessions and file transcription")
+	fmt.Println("  devices   List audio capture devices")
 	fmt.Println("  config    Show current configuration and config file location")
 	fmt.Println("  download-model  Download a Whisper model")
 	fmt.Println("  stop      Find and stop all running transcriber processes")
@@ -28,16 +35,40 @@ func printUsage() {
 	fmt.Println("  --output string")
 	fmt.Println("        Output directory for transcriptions (default \".\")")
 	fmt.Println("  --input string")
-	fmt.Println("        Input directory for processing (defaults to temp directory)")
+	fmt.Println("        Input file or directory for processing (defaults to temp directory).")
+	fmt.Println("        In run mode, an audio stream to transcribe instead of a device: - for stdin, a file or FIFO, or a URL")
 	fmt.Println("  --config string")
 	fmt.Println("        Path to configuration file (defaults to standard config location ~/.transcriber/)")
 	fmt.Println("  --duration string")
-	fmt.Println("        Recording duration for run mode (e.g., 30s, 2m, 1h) (default \"30m\")")
+	fmt.Println("        Recording duration for run mode (e.g., 30s, 2m, 1h); 0 runs until stopped (default \"0\")")
+	fmt.Println("  --resume string")
+	fmt.Println("        Session ID or manifest of an interrupted run to continue")
+	fmt.Println("  --device string")
+	fmt.Println("        Capture device for run and serve mode, as listed by devices (overrides input_device)")
+	fmt.Println("  --source string")
+	fmt.Println("        What run and serve mode record: mic, system (audio output), mixed or dual (overrides capture_source)")
+	fmt.Println("  --profile string")
+	fmt.Println("        Vocabulary and initial prompt profile from the profiles config key (overrides profile)")
+	fmt.Println("  --debug-filter")
+	fmt.Println("        Log text removed by the hallucination filter and record it in <transcript>.filtered.jsonl (sets filter_debug)")
+	fmt.Println("  --addr string")
+	fmt.Println("        Listen address for serve mode (default \"127.0.0.1:8765\")")
 	fmt.Println("  --model string")
 	fmt.Println("        Model name to download (default \"ggml-large-v3-turbo-q5_0\")")
 	fmt.Println("\nExamples:")
 	fmt.Printf("  %s run --output ./transcriptions\n", os.Args[0])
 	fmt.Printf("  %s run --duration 2m --output ./transcriptions\n", os.Args[0])
+	fmt.Printf("  %s run --resume 20250101_120000 --output ./transcriptions\n", os.Args[0])
+	fmt.Printf("  %s retry 20250101_120000 --output ./transcriptions\n", os.Args[0])
+	fmt.Printf("  %s process --input ./audio --output ./transcriptions\n", os.Args[0])
+	fmt.Printf("  %s postprocess ./transcriptions/run_20250101_120000.txt\n", os.Args[0])
+	fmt.Printf("  %s serve --addr 127.0.0.1:8765 --output ./transcriptions\n", os.Args[0])
+	fmt.Printf("  %s devices\n", os.Args[0])
+	fmt.Printf("  %s run --profile engineering --output ./transcriptions\n", os.Args[0])
+	fmt.Printf("  %s run --device pulse:alsa_input.usb-mic --output ./transcriptions\n", os.Args[0])
+	fmt.Printf("  %s run --source mixed --output ./transcriptions\n", os.Args[0])
+	fmt.Printf("  %s run --input rtsp://bridge.example/conf\n", os.Args[0])
+	fmt.Printf("  arecord -f S16_LE -r 16000 -c 1 -t wav | %s run --input - --output ./transcriptions\n", os.Args[0])
 	fmt.Printf("  %s config\n", os.Args[0])
 	fmt.Printf("  %s download-model --model base\n", os.Args[0])
 }
@@ -132,6 +163,22 @@ func killAllProcesses() error {
 	return nil
 }
 
+// parseDuration parses a session duration such as 30s, 2m or 1h.
+// Zero means record until interrupted.
+func parseDuration(value string) (time.Duration, error) {
+	if value == "" || value == "0" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("duration must not be negative")
+	}
+	return d, nil
+}
+
 func getDefaultConfigPath() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -164,6 +211,12 @@ func main() {
 	// Validate command
 	validCommands := map[string]bool{
 		"run":            true,
+		"process":        true,
+		"serve":          true,
+		"retry":          true,
+		"postprocess":    true,
+		"reveal":         true,
+		"devices":        true,
 		"config":         true,
 		"download-model": true,
 		"stop":           true,
@@ -179,31 +232,139 @@ func main() {
 	// Parse flags for the subcommand
 	flagSet := flag.NewFlagSet(command, flag.ExitOnError)
 	var (
-		outputDir  = flagSet.String("output", ".", "Output directory for transcriptions")
-		configPath = flagSet.String("config", getDefaultConfigPath(), "Path to configuration file (defaults to ~/.transcriber/)")
-		modelName  = flagSet.String("model", "ggml-large-v3-turbo-q5_0", "Model name to download")
+		outputDir   = flagSet.String("output", ".", "Output directory for transcriptions")
+		duration    = flagSet.String("duration", "0", "Recording duration for run mode (e.g., 30s, 2m, 1h; 0 runs until stopped)")
+		inputPath   = flagSet.String("input", "", "Input file or directory for processing (defaults to temp directory)")
+		configPath  = flagSet.String("config", getDefaultConfigPath(), "Path to configuration file (defaults to ~/.transcriber/)")
+		resume      = flagSet.String("resume", "", "Session ID or manifest to resume in run mode")
+		device      = flagSet.String("device", "", "Capture device for run and serve mode")
+		source      = flagSet.String("source", "", "Capture source for run and serve mode: mic, system, mixed or dual")
+		profile     = flagSet.String("profile", "", "Vocabulary and initial prompt profile to use")
+		debugFilter = flagSet.Bool("debug-filter", false, "Record text removed by the hallucination filter")
+		addr        = flagSet.String("addr", "127.0.0.1:8765", "Listen address for serve mode")
+		modelName   = flagSet.String("model", "ggml-large-v3-turbo-q5_0", "Model name to download")
 	)
 
 	flagSet.Usage = printUsage
-	flagSet.Parse(os.Args[2:])
+
+	// Commands like retry take a positional argument before the flags
+	args := os.Args[2:]
+	var positional string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		positional, args = args[0], args[1:]
+	}
+	flagSet.Parse(args)
+	if positional == "" {
+		positional = flagSet.Arg(0)
+	}
 
 	transcriber, err := NewTranscriber(*configPath)
 	if err != nil {
 		fmt.Printf("Error initializing transcriber: %v\n", err)
 		os.Exit(1)
 	}
+	if *device != "" {
+		transcriber.SetInputDevice(*device)
+	}
+	if *source != "" {
+		if err := transcriber.SetCaptureSource(*source); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if *profile != "" {
+		if err := transcriber.SetProfile(*profile); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *debugFilter {
+		transcriber.SetFilterDebug(true)
+	}
 
 	switch command {
 
 	case "run":
+		sessionDuration, err := parseDuration(*duration)
+		if err != nil {
+			fmt.Printf("Invalid duration %q: %v\n", *duration, err)
+			os.Exit(1)
+		}
+		if *inputPath != "" {
+			if err := transcriber.SetInputStream(*inputPath); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
 		printProcessInfo()
-		if err := transcriber.RunTranscribe(*outputDir, true); err != nil {
+		if *resume != "" {
+			err = transcriber.ResumeTranscribe(*resume, *outputDir, sessionDuration, true)
+		} else {
+			err = transcriber.RunTranscribe(*outputDir, sessionDuration, true)
+		}
+		if err != nil {
 			fmt.Printf("Error in run transcribe: %v\n", err)
 			os.Exit(1)
 		}
 
+	case "process":
+		if err := transcriber.ProcessFiles(*inputPath, *outputDir); err != nil {
+			fmt.Printf("Error in process: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "retry":
+		if positional == "" {
+			fmt.Println("Please specify the session to retry")
+			printUsage()
+			os.Exit(1)
+		}
+		if err := transcriber.RetrySession(positional, *outputDir); err != nil {
+			fmt.Printf("Error in retry: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "postprocess":
+		if positional == "" {
+			fmt.Println("Please specify the transcript to post-process")
+			printUsage()
+			os.Exit(1)
+		}
+		if err := transcriber.PostprocessFile(positional); err != nil {
+			fmt.Printf("Error in postprocess: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "reveal":
+		if positional == "" {
+			fmt.Println("Please specify the transcript to reveal")
+			printUsage()
+			os.Exit(1)
+		}
+		if err := transcriber.RevealRedactions(positional); err != nil {
+			fmt.Printf("Error in reveal: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "serve":
+		printProcessInfo()
+		if err := transcriber.Serve(*addr, *outputDir); err != nil {
+			fmt.Printf("Error in serve: %v\n", err)
+			os.Exit(1)
+		}
+
+	case "devices":
+		if err := transcriber.ListDevices(); err != nil {
+			fmt.Printf("Error listing devices: %v\n", err)
+			os.Exit(1)
+		}
+
 	case "config":
 		config := transcriber.GetConfig()
+		if config.APIKey != "" {
+			config.APIKey = "********"
+		}
 		configJSON, _ := json.MarshalIndent(config, "", "  ")
 		fmt.Printf("Current configuration:\n%s\n\n", string(configJSON))
 		fmt.Printf("Config file location: %s\n", transcriber.GetConfigPath())
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// AudioDevice is a capture device as ffmpeg addresses it. Spec is the value
+// for input_device or --device.
+type AudioDevice struct {
+	Backend     string // ffmpeg input format: alsa, pulse, avfoundation or dshow
+	Name        string
+	Description string
+}
+
+// Spec returns the device as "<backend>:<name>"
+func (d AudioDevice) Spec() string {
+	return d.Backend + ":" + d.Name
+}
+
+// ffmpeg input formats that may prefix an input_device value
+var captureBackends = map[string]bool{
+	"alsa":         true,
+	"pulse":        true,
+	"avfoundation": true,
+	"dshow":        true,
+}
+
+// Capture sources: the input device, the system's output, both mixed, or
+// both in separate channels
+var supportedCaptureSources = map[string]bool{
+	"mic":    true,
+	"system": true,
+	"mixed":  true,
+	"dual":   true,
+}
+
+// PulseAudio and PipeWire name for the monitor of the default output
+const defaultMonitorDevice = "pulse:@DEFAULT_MONITOR@"
+
+// defaultCaptureBackend returns the ffmpeg input format used when
+// input_device has no backend prefix
+func defaultCaptureBackend() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "avfoundation"
+	case "linux":
+		return "alsa"
+	case "windows":
+		return "dshow"
+	default:
+		return "pulse"
+	}
+}
+
+// splitDeviceSpec splits an input_device value into its ffmpeg input format
+// and device name. Values without a known backend prefix, such as
+// "hw:1,0" or ":0", use the platform's default backend.
+func splitDeviceSpec(spec string) (backend, name string) {
+	if prefix, rest, ok := strings.Cut(spec, ":"); ok && captureBackends[prefix] {
+		return prefix, rest
+	}
+	return defaultCaptureBackend(), spec
+}
+
+// listAudioDevices returns the capture devices of every backend available on
+// this platform. Backends whose tools are missing are left out.
+func listAudioDevices() ([]AudioDevice, error) {
+	var devices []AudioDevice
+	var errs []string
+
+	collect := func(found []AudioDevice, err error) {
+		if err != nil {
+			errs = append(errs, err.Error())
+			return
+		}
+		devices = append(devices, found...)
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		collect(listAVFoundationDevices())
+	case "windows":
+		collect(listDShowDevices())
+	default:
+		collect(listALSADevices())
+		collect(listPulseDevices())
+	}
+
+	if len(devices) == 0 && len(errs) > 0 {
+		return nil, fmt.Errorf("no capture devices found: %s", strings.Join(errs, "; "))
+	}
+	return devices, nil
+}
+
+// listALSADevices parses `arecord -l`
+func listALSADevices() ([]AudioDevice, error) {
+	output, err := exec.Command("arecord", "-l").Output()
+	if err != nil {
+		return nil, fmt.Errorf("arecord: %v", err)
+	}
+	return parseArecordList(string(output)), nil
+}
+
+// Matches "card 1: USB [USB Audio], device 0: USB Audio [USB Audio]"
+var arecordCardPattern = regexp.MustCompile(`^card (\d+): \S+ \[(.*)\], device (\d+): (.*?)(?: \[.*\])?$`)
+
+func parseArecordList(output string) []AudioDevice {
+	devices := []AudioDevice{{Backend: "alsa", Name: "default", Description: "Default ALSA device"}}
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		m := arecordCardPattern.FindStringSubmatch(strings.TrimSpace(scanner.Text()))
+		if m == nil {
+			continue
+		}
+		devices = append(devices, AudioDevice{
+			Backend:     "alsa",
+			Name:        fmt.Sprintf("plughw:%s,%s", m[1], m[3]),
+			Description: fmt.Sprintf("%s - %s", m[2], m[4]),
+		})
+	}
+	return devices
+}
+
+// listPulseDevices parses `pactl list short sources`, which also covers
+// PipeWire through its PulseAudio server
+func listPulseDevices() ([]AudioDevice, error) {
+	output, err := exec.Command("pactl", "list", "short", "sources").Output()
+	if err != nil {
+		return nil, fmt.Errorf("pactl: %v", err)
+	}
+	return parsePactlSources(string(output)), nil
+}
+
+func parsePactlSources(output string) []AudioDevice {
+	var devices []AudioDevice
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		// index, name, module, sample spec, state
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		description := "Input"
+		if strings.HasSuffix(fields[1], ".monitor") {
+			description = "Monitor of system output"
+		}
+		if len(fields) >= 5 {
+			description += ", " + strings.ToLower(fields[4])
+		}
+		devices = append(devices, AudioDevice{Backend: "pulse", Name: fields[1], Description: description})
+	}
+	return devices
+}
+
+// listAVFoundationDevices parses the device list ffmpeg prints on stderr
+func listAVFoundationDevices() ([]AudioDevice, error) {
+	output, err := ffmpegDeviceList("-f", "avfoundation", "-list_devices", "true", "-i", "")
+	if err != nil {
+		return nil, err
+	}
+	return parseAVFoundationList(output), nil
+}
+
+// Matches "[AVFoundation indev @ 0x7f8] [0] MacBook Pro Microphone"
+var avfoundationDevicePattern = regexp.MustCompile(`\] \[(\d+)\] (.+)$`)
+
+func parseAVFoundationList(output string) []AudioDevice {
+	var devices []AudioDevice
+	inAudio := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.Contains(line, "AVFoundation audio devices"):
+			inAudio = true
+			continue
+		case strings.Contains(line, "AVFoundation video devices"):
+			inAudio = false
+			continue
+		}
+		if !inAudio {
+			continue
+		}
+		if m := avfoundationDevicePattern.FindStringSubmatch(line); m != nil {
+			devices = append(devices, AudioDevice{
+				Backend:     "avfoundation",
+				Name:        ":" + m[2],
+				Description: "Audio device " + m[1],
+			})
+		}
+	}
+	return devices
+}
+
+// listDShowDevices parses the device list ffmpeg prints on stderr
+func listDShowDevices() ([]AudioDevice, error) {
+	output, err := ffmpegDeviceList("-list_devices", "true", "-f", "dshow", "-i", "dummy")
+	if err != nil {
+		return nil, err
+	}
+	return parseDShowList(output), nil
+}
+
+// Matches `[dshow @ 000001] "Microphone (USB Audio)" (audio)` and the older
+// `[dshow @ 000001]  "Microphone (USB Audio)"` listed under a section header
+var dshowDevicePattern = regexp.MustCompile(`\]\s+"([^"]+)"\s*(\((audio|video|none)\))?\s*$`)
+
+func parseDShowList(output string) []AudioDevice {
+	var devices []AudioDevice
+	inAudio := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.Contains(line, "DirectShow audio devices"):
+			inAudio = true
+			continue
+		case strings.Contains(line, "DirectShow video devices"):
+			inAudio = false
+			continue
+		case strings.Contains(line, "Alternative name"):
+			continue
+		}
+
+		m := dshowDevicePattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		if m[3] == "audio" || (m[3] == "" && inAudio) {
+			devices = append(devices, AudioDevice{Backend: "dshow", Name: m[1], Description: "DirectShow audio device"})
+		}
+	}
+	return devices
+}
+
+// ffmpegDeviceList runs an ffmpeg device listing. ffmpeg prints the list on
+// stderr and then fails because no real input was opened, so the exit status
+// is ignored when there is output.
+func ffmpegDeviceList(args ...string) (string, error) {
+	cmd := exec.Command("ffmpeg", append([]string{"-hide_banner"}, args...)...)
+	output, err := cmd.CombinedOutput()
+	if len(output) == 0 && err != nil {
+		return "", fmt.Errorf("ffmpeg: %v", err)
+	}
+	return string(output), nil
+}
+
+// printAudioDevices lists the capture devices grouped by backend, marking
+// the current one
+func printAudioDevices(current string) error {
+	devices, err := listAudioDevices()
+	if err != nil {
+		return err
+	}
+	if len(devices) == 0 {
+		fmt.Println("No capture devices found.")
+		return nil
+	}
+
+	currentBackend, currentName := splitDeviceSpec(current)
+	backend := ""
+	for _, device := range devices {
+		if device.Backend != backend {
+			backend = device.Backend
+			fmt.Printf("\n%s:\n", backend)
+		}
+		marker := " "
+		if device.Backend == currentBackend && device.Name == currentName {
+			marker = "*"
+		}
+		fmt.Printf(" %s %-50s %s\n", marker, device.Spec(), device.Description)
+	}
+
+	fmt.Println("\nSet input_device in the config file or pass --device to choose a device.")
+	return nil
+}
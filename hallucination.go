@@ -0,0 +1,199 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Phrases whisper tends to produce on silence or noise, dropped when they
+// make up a whole segment
+var defaultHallucinationPhrases = []string{
+	"thank you for watching",
+	"thanks for watching",
+	"please subscribe",
+	"like and subscribe",
+	"don't forget to like and subscribe",
+	"see you in the next video",
+	"subtitles by the amara.org community",
+}
+
+// filteredText is one piece of text removed from a transcription, recorded
+// when filter_debug is on. Offsets are on the session timeline.
+type filteredText struct {
+	Chunk  int     `json:"chunk"`
+	Start  float64 `json:"start"`
+	End    float64 `json:"end"`
+	Reason string  `json:"reason"`
+	Text   string  `json:"text"`
+}
+
+// normalizePhrase lowercases text and strips punctuation for comparison
+func normalizePhrase(text string) string {
+	var words []string
+	for _, word := range strings.Fields(text) {
+		if w := normalizeWord(word); w != "" {
+			words = append(words, w)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// filterHallucinations removes segments whisper likely made up and collapses
+// repeated text. It returns the kept segments and what was removed, with
+// offsets relative to the transcribed audio.
+func (t *Transcriber) filterHallucinations(segments []TranscriptSegment) ([]TranscriptSegment, []filteredText) {
+	if t.config.HallucinationFilter == "off" {
+		return segments, nil
+	}
+
+	phrases := make(map[string]bool)
+	for _, phrase := range t.config.HallucinationPhrases {
+		phrases[normalizePhrase(phrase)] = true
+	}
+
+	var removed []filteredText
+	drop := func(seg TranscriptSegment, reason, text string) {
+		removed = append(removed, filteredText{Start: seg.Start, End: seg.End, Reason: reason, Text: strings.TrimSpace(text)})
+	}
+
+	kept := make([]TranscriptSegment, 0, len(segments))
+	for _, seg := range segments {
+		if p, ok := averageTokenProb(seg); ok && t.config.MinAvgTokenProb != nil && p < *t.config.MinAvgTokenProb {
+			drop(seg, fmt.Sprintf("low average token probability %.2f", p), seg.Text)
+			continue
+		}
+		if phrases[normalizePhrase(seg.Text)] {
+			drop(seg, "hallucination phrase", seg.Text)
+			continue
+		}
+
+		if collapsed, cut := collapseRepeats(seg.Text, t.config.RepeatThreshold); cut != "" {
+			drop(seg, "repeated words", cut)
+			seg.Text = collapsed
+			seg.Tokens = nil // No longer match the text
+		}
+		kept = append(kept, seg)
+	}
+
+	// Collapse runs of the same line into its first occurrence
+	out := kept[:0]
+	for i := 0; i < len(kept); {
+		j := i + 1
+		for j < len(kept) && kept[j].Speaker == kept[i].Speaker &&
+			normalizePhrase(kept[j].Text) == normalizePhrase(kept[i].Text) {
+			j++
+		}
+		seg := kept[i]
+		if j-i >= t.config.RepeatThreshold {
+			for _, dup := range kept[i+1 : j] {
+				drop(dup, "repeated segment", dup.Text)
+			}
+			seg.End = kept[j-1].End
+		} else {
+			j = i + 1
+		}
+		out = append(out, seg)
+		i = j
+	}
+	return out, removed
+}
+
+// averageTokenProb returns the mean token probability of a segment, if the
+// backend reported any
+func averageTokenProb(seg TranscriptSegment) (float64, bool) {
+	if len(seg.Tokens) == 0 {
+		return 0, false
+	}
+	sum := 0.0
+	for _, token := range seg.Tokens {
+		sum += token.P
+	}
+	return sum / float64(len(seg.Tokens)), true
+}
+
+// collapseRepeats replaces any run of an n-gram repeated threshold or more
+// times in a row with a single occurrence. It returns the new text and the
+// words removed, or "" if nothing changed.
+func collapseRepeats(text string, threshold int) (string, string) {
+	if threshold < 2 {
+		return text, ""
+	}
+
+	words := strings.Fields(text)
+	var cut []string
+	for n := 1; n*threshold <= len(words); n++ {
+		for i := 0; i+n*threshold <= len(words); i++ {
+			repeats := 1
+			for i+(repeats+1)*n <= len(words) && sameWords(words[i:i+n], words[i+repeats*n:i+(repeats+1)*n]) {
+				repeats++
+			}
+			if repeats < threshold {
+				continue
+			}
+			cut = append(cut, words[i+n:i+repeats*n]...)
+			words = append(words[:i+n], words[i+repeats*n:]...)
+		}
+	}
+	if len(cut) == 0 {
+		return text, ""
+	}
+	return strings.Join(words, " "), strings.Join(cut, " ")
+}
+
+func sameWords(a, b []string) bool {
+	for i := range a {
+		if normalizeWord(a[i]) != normalizeWord(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// logFiltered reports text removed from a chunk and, with filter_debug on,
+// appends it to the session's filter log
+func (t *Transcriber) logFiltered(session *Session, chunk AudioChunk, removed []filteredText) {
+	if len(removed) == 0 {
+		return
+	}
+	fmt.Printf("Filtered %d likely hallucination(s) from chunk %d\n", len(removed), chunk.Num)
+	if !t.config.FilterDebug {
+		return
+	}
+
+	for i := range removed {
+		removed[i].Chunk = chunk.Num
+		removed[i].Start += chunk.WindowStart
+		removed[i].End += chunk.WindowStart
+		removed[i].Text = t.redactor.mask(removed[i].Text)
+		fmt.Printf("  [%s] %s: %q\n", formatTimestamp(int(removed[i].Start)), removed[i].Reason, removed[i].Text)
+	}
+	if err := session.logFiltered(removed); err != nil {
+		fmt.Printf("Warning: failed to write filter log: %v\n", err)
+	}
+}
+
+// FilterLogPath returns the file recording text removed by the filters
+func (s *Session) FilterLogPath() string {
+	return s.OutputPath + ".filtered.jsonl"
+}
+
+func (s *Session) logFiltered(removed []filteredText) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.FilterLogPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	enc := json.NewEncoder(f)
+	for _, entry := range removed {
+		if err := enc.Encode(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
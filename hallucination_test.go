@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMinAvgTokenProbConfig(t *testing.T) {
+	// writeConfig writes a config with its own temp_dir plus extra settings
+	writeConfig := func(t *testing.T, extra string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.json")
+		config := fmt.Sprintf(`{"temp_dir": %q%s}`, t.TempDir(), extra)
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name  string
+		extra string
+		want  float64
+	}{
+		{"unset keeps the default", ``, 0.2},
+		{"explicit value", `, "min_avg_token_prob": 0.5`, 0.5},
+		{"explicit zero", `, "min_avg_token_prob": 0`, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &Transcriber{configPath: writeConfig(t, tt.extra)}
+			if err := tr.loadConfig(); err != nil {
+				t.Fatalf("loadConfig: %v", err)
+			}
+			if got := *tr.config.MinAvgTokenProb; got != tt.want {
+				t.Errorf("min_avg_token_prob = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := (&Transcriber{configPath: writeConfig(t, `, "min_avg_token_prob": -0.1`)}).loadConfig(); err == nil {
+		t.Error("expected an error for a negative min_avg_token_prob")
+	}
+}
+
+func TestFilterHallucinationsTokenProbability(t *testing.T) {
+	segments := func() []TranscriptSegment {
+		return []TranscriptSegment{
+			{Text: "we ship on friday", Tokens: []TranscriptionToken{{Text: "we", P: 0.9}, {Text: "ship", P: 0.8}}},
+			{Text: "mumbled words", Tokens: []TranscriptionToken{{Text: "mumbled", P: 0.05}, {Text: "words", P: 0.1}}},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		minProb  *float64
+		wantKept int
+	}{
+		{"default threshold drops the unlikely segment", floatValue(0.2), 1},
+		{"zero keeps every segment", floatValue(0), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr := &Transcriber{config: Config{HallucinationFilter: "on", RepeatThreshold: 3, MinAvgTokenProb: tt.minProb}}
+			kept, removed := tr.filterHallucinations(segments())
+			if len(kept) != tt.wantKept || len(removed) != 2-tt.wantKept {
+				t.Errorf("kept %d and removed %d segment(s), want %d kept", len(kept), len(removed), tt.wantKept)
+			}
+		})
+	}
+}
+
+func floatValue(v float64) *float64 {
+	return &v
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// openAIBackend uploads audio to an OpenAI-compatible
+// /v1/audio/transcriptions endpoint
+type openAIBackend struct {
+	config *Config
+	client *http.Client
+}
+
+func newOpenAIBackend(config *Config) *openAIBackend {
+	return &openAIBackend{
+		config: config,
+		client: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (b *openAIBackend) Transcribe(audioFile, outputPath, prompt string) (*TranscriptionResult, error) {
+	fields := map[string]string{
+		"model":                     b.config.APIModel,
+		"response_format":           "verbose_json",
+		"timestamp_granularities[]": "segment",
+		"language":                  languageCode(b.config.Language),
+		"prompt":                    prompt,
+	}
+	b.config.Whisper.openAIFields(fields)
+
+	// Translation has its own endpoint, which always produces English
+	endpoint := "/audio/transcriptions"
+	if b.config.Whisper.Translate {
+		endpoint = "/audio/translations"
+		delete(fields, "language")
+		delete(fields, "timestamp_granularities[]")
+	}
+
+	body, contentType, err := newMultipartUpload(audioFile, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimRight(b.config.APIBaseURL, "/") + endpoint
+	req, err := http.NewRequest(http.MethodPost, url, body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid api_base_url: %v", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	if b.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+b.config.APIKey)
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("transcription API request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcription API response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("transcription API returned HTTP %d: %s", resp.StatusCode, apiErrorMessage(data))
+	}
+
+	result, err := parseVerboseJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse transcription API response: %v", err)
+	}
+	return result, nil
+}
+
+// apiErrorMessage extracts the message from an OpenAI-style error body,
+// falling back to the raw body
+func apiErrorMessage(data []byte) string {
+	var body struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &body); err == nil && body.Error.Message != "" {
+		return body.Error.Message
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Whisper language names mapped to the ISO-639-1 codes the API expects
+var languageCodes = map[string]string{
+	"english":    "en",
+	"spanish":    "es",
+	"french":     "fr",
+	"german":     "de",
+	"italian":    "it",
+	"portuguese": "pt",
+	"dutch":      "nl",
+	"russian":    "ru",
+	"chinese":    "zh",
+	"japanese":   "ja",
+	"korean":     "ko",
+	"arabic":     "ar",
+	"hindi":      "hi",
+	"turkish":    "tr",
+	"polish":     "pl",
+	"ukrainian":  "uk",
+	"swedish":    "sv",
+	"norwegian":  "no",
+	"danish":     "da",
+	"finnish":    "fi",
+	"greek":      "el",
+	"hebrew":     "he",
+	"indonesian": "id",
+	"vietnamese": "vi",
+	"thai":       "th",
+	"tamil":      "ta",
+	"malayalam":  "ml",
+}
+
+// languageCode converts the configured language to an ISO-639-1 code. An
+// empty result lets the API detect the language.
+func languageCode(language string) string {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if language == "" || language == "auto" {
+		return ""
+	}
+	if len(language) == 2 {
+		return language
+	}
+	return languageCodes[language]
+}
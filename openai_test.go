@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOpenAIBackendTranscribe(t *testing.T) {
+	upload := &uploadRecorder{
+		status: http.StatusOK,
+		body:   `{"language":"english","text":"Hi all","segments":[{"start":0,"end":1.2,"text":" Hi all","avg_logprob":-0.05}]}`,
+	}
+	srv := httptest.NewServer(upload)
+	defer srv.Close()
+
+	config := &Config{
+		APIBaseURL: srv.URL + "/v1/",
+		APIKey:     "sk-test",
+		APIModel:   "whisper-1",
+		Language:   "English",
+		Whisper:    WhisperOptions{Temperature: 0.2},
+	}
+	result, err := newOpenAIBackend(config).Transcribe(writeTestAudio(t), "", "Glossary: Kubernetes.")
+	if err != nil {
+		t.Fatalf("Transcribe: %v", err)
+	}
+
+	if upload.path != "/v1/audio/transcriptions" {
+		t.Errorf("posted to %q, want /v1/audio/transcriptions", upload.path)
+	}
+	if got := upload.header.Get("Authorization"); got != "Bearer sk-test" {
+		t.Errorf("Authorization = %q, want Bearer sk-test", got)
+	}
+	wantFields := map[string]string{
+		"model":                     "whisper-1",
+		"response_format":           "verbose_json",
+		"timestamp_granularities[]": "segment",
+		"language":                  "en",
+		"prompt":                    "Glossary: Kubernetes.",
+		"temperature":               "0.2",
+	}
+	if !reflect.DeepEqual(upload.fields, wantFields) {
+		t.Errorf("fields = %v, want %v", upload.fields, wantFields)
+	}
+
+	if len(result.Segments) != 1 || result.Segments[0].Text != "Hi all" || result.Segments[0].End != 1.2 {
+		t.Errorf("unexpected segments: %+v", result.Segments)
+	}
+}
+
+func TestOpenAIBackendTranslate(t *testing.T) {
+	upload := &uploadRecorder{status: http.StatusOK, body: `{"text":"Good morning"}`}
+	srv := httptest.NewServer(upload)
+	defer srv.Close()
+
+	config := &Config{
+		APIBaseURL: srv.URL,
+		APIModel:   "whisper-1",
+		Language:   "German",
+		Whisper:    WhisperOptions{Translate: true},
+	}
+	result, err := newOpenAIBackend(config).Transcribe(writeTestAudio(t), "", "")
+	if err != nil {
+		t.Fatalf("Transcribe: %v", err)
+	}
+
+	if upload.path != "/audio/translations" {
+		t.Errorf("posted to %q, want /audio/translations", upload.path)
+	}
+	if _, ok := upload.fields["language"]; ok {
+		t.Error("translation request should not send a language")
+	}
+	if _, ok := upload.fields["timestamp_granularities[]"]; ok {
+		t.Error("translation request should not send timestamp granularities")
+	}
+	if got := upload.header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q, want none without an api_key", got)
+	}
+	if len(result.Segments) != 1 || result.Segments[0].Text != "Good morning" {
+		t.Errorf("unexpected segments: %+v", result.Segments)
+	}
+}
+
+func TestOpenAIBackendHTTPError(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		want   string
+	}{
+		{
+			name:   "OpenAI error body",
+			status: http.StatusUnauthorized,
+			body:   `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error"}}`,
+			want:   "HTTP 401: Incorrect API key provided",
+		},
+		{
+			name:   "plain body",
+			status: http.StatusBadGateway,
+			body:   "upstream unavailable\n",
+			want:   "HTTP 502: upstream unavailable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(&uploadRecorder{status: tt.status, body: tt.body})
+			defer srv.Close()
+
+			_, err := newOpenAIBackend(&Config{APIBaseURL: srv.URL, APIKey: "bad"}).Transcribe(writeTestAudio(t), "", "")
+			if err == nil {
+				t.Fatalf("expected an error for HTTP %d", tt.status)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error %q should contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLanguageCode(t *testing.T) {
+	tests := map[string]string{
+		"English":  "en",
+		" german ": "de",
+		"fr":       "fr",
+		"auto":     "",
+		"":         "",
+		"Klingon":  "",
+	}
+	for language, want := range tests {
+		if got := languageCode(language); got != want {
+			t.Errorf("languageCode(%q) = %q, want %q", language, got, want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// chunkOverlapper prepends the last overlapSecs of the previous chunk's audio
+// to each chunk, so words spoken across a boundary are heard whole at least
+// once. The tail is kept in memory because the previous chunk's file may
+// already be transcribed and removed.
+type chunkOverlapper struct {
+	overlapSecs int
+	tail        []int16
+	tailEnd     float64 // timeline offset where the kept tail ends
+	channels    int
+}
+
+func newChunkOverlapper(overlapSecs int) *chunkOverlapper {
+	return &chunkOverlapper{overlapSecs: overlapSecs}
+}
+
+// apply rewrites the chunk's audio file in place as its overlap window and
+// sets WindowStart accordingly. Chunks that don't directly follow the previous
+// one are left untouched.
+func (o *chunkOverlapper) apply(chunk *AudioChunk) error {
+	chunk.WindowStart = chunk.Start
+	if o.overlapSecs <= 0 {
+		return nil
+	}
+
+	audio, err := readWAV(chunk.Path)
+	if err != nil {
+		return fmt.Errorf("failed to read chunk audio for overlap: %v", err)
+	}
+
+	prevTail, prevEnd, prevChannels := o.tail, o.tailEnd, o.channels
+
+	// Keep this chunk's own tail for the next window
+	keep := o.overlapSecs * audio.samplesPerSec()
+	if keep > len(audio.Samples) {
+		keep = len(audio.Samples)
+	}
+	o.tail = append([]int16(nil), audio.Samples[len(audio.Samples)-keep:]...)
+	o.tailEnd = chunk.End
+	o.channels = audio.channelCount()
+
+	// Only overlap with audio that ends exactly where this chunk begins
+	if len(prevTail) == 0 || prevChannels != audio.channelCount() ||
+		chunk.Start-prevEnd > 0.05 || prevEnd-chunk.Start > 0.05 {
+		return nil
+	}
+
+	window := &wavAudio{
+		SampleRate: audio.SampleRate,
+		Channels:   audio.Channels,
+		Samples:    append(prevTail, audio.Samples...),
+	}
+	if err := writeWAV(chunk.Path, window); err != nil {
+		return fmt.Errorf("failed to write overlap window: %v", err)
+	}
+	chunk.WindowStart = chunk.Start - float64(len(prevTail))/float64(audio.samplesPerSec())
+	return nil
+}
+
+// normalizeWord lowercases a word and strips punctuation for comparison
+func normalizeWord(word string) string {
+	return strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}))
+}
+
+// Words at the start of the next chunk that may come before the repeated
+// run: the partially heard first word of the overlap, plus a little slack
+const overlapLeadWords = 3
+
+// overlapWordCount returns how many leading words of next repeat the end of
+// prev because both chunks transcribed the shared overlap audio. The repeat
+// must be anchored at both ends: a run of words that ends prev and starts
+// within the first overlapLeadWords words of next. Words before the run are
+// the partially heard start of the overlap and are counted as well. When the
+// overlap audio held no speech, nothing is repeated and 0 is returned.
+func overlapWordCount(prev, next string, overlapSecs int) int {
+	if prev == "" || next == "" || overlapSecs <= 0 {
+		return 0
+	}
+
+	// Roughly how many words can be spoken in the overlap, with margin
+	window := overlapSecs * 5
+	if window < 8 {
+		window = 8
+	}
+
+	prevWords := strings.Fields(prev)
+	if len(prevWords) > window {
+		prevWords = prevWords[len(prevWords)-window:]
+	}
+	tail := make([]string, len(prevWords))
+	for i, w := range prevWords {
+		tail[i] = normalizeWord(w)
+	}
+
+	nextWords := strings.Fields(next)
+	if len(nextWords) > window {
+		nextWords = nextWords[:window]
+	}
+	head := make([]string, len(nextWords))
+	for i, w := range nextWords {
+		head[i] = normalizeWord(w)
+	}
+
+	bestLen, bestEnd := 0, 0
+	for i := 0; i <= overlapLeadWords && i < len(head); i++ {
+		for n := len(head) - i; n > bestLen; n-- {
+			if n <= len(tail) && sameNormalized(head[i:i+n], tail[len(tail)-n:]) {
+				bestLen, bestEnd = n, i+n
+				break
+			}
+		}
+	}
+
+	// A single shared word is too likely to be a coincidence
+	if bestLen < 2 {
+		return 0
+	}
+	return bestEnd
+}
+
+// sameNormalized reports whether two runs of normalized words match, never
+// matching on words that were only punctuation
+func sameNormalized(a, b []string) bool {
+	for i := range a {
+		if a[i] == "" || a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// dropLeadingWords removes the first n words from segments. Segments that
+// lose all their words are removed; a partially trimmed segment keeps its
+// timing.
+func dropLeadingWords(segments []TranscriptSegment, n int) []TranscriptSegment {
+	for n > 0 && len(segments) > 0 {
+		words := strings.Fields(segments[0].Text)
+		if len(words) <= n {
+			n -= len(words)
+			segments = segments[1:]
+			continue
+		}
+		trimmed := segments[0]
+		trimmed.Text = strings.Join(words[n:], " ")
+		trimmed.Tokens = nil
+		segments = append([]TranscriptSegment{trimmed}, segments[1:]...)
+		n = 0
+	}
+	return segments
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOverlapWordCount(t *testing.T) {
+	tests := []struct {
+		name        string
+		prev, next  string
+		overlapSecs int
+		want        int
+	}{
+		{
+			name:        "repeated run at the boundary",
+			prev:        "so we agreed to ship the release next week",
+			next:        "release next week and then start planning",
+			overlapSecs: 2,
+			want:        3,
+		},
+		{
+			name:        "partially heard first word before the run",
+			prev:        "so we agreed to ship the release next week",
+			next:        "ease next week and then start planning",
+			overlapSecs: 2,
+			want:        3,
+		},
+		{
+			name:        "punctuation and case differ",
+			prev:        "Let's look at the numbers.",
+			next:        "at the Numbers, revenue grew",
+			overlapSecs: 2,
+			want:        3,
+		},
+		{
+			name:        "no repeat when the overlap was a pause",
+			prev:        "and that wraps up what happened at the end of the quarter.",
+			next:        "Right, moving on, let us look at the results of the survey we ran",
+			overlapSecs: 2,
+			want:        0,
+		},
+		{
+			name:        "shared run that does not end prev",
+			prev:        "we looked at the results of the survey and then left",
+			next:        "the results of the survey were good",
+			overlapSecs: 2,
+			want:        0,
+		},
+		{
+			name:        "shared run too deep into next",
+			prev:        "we will meet again next week",
+			next:        "okay so as I said before next week works",
+			overlapSecs: 2,
+			want:        0,
+		},
+		{
+			name:        "single shared word",
+			prev:        "that was the plan",
+			next:        "plan two starts now",
+			overlapSecs: 2,
+			want:        0,
+		},
+		{
+			name:        "overlap disabled",
+			prev:        "ship the release next week",
+			next:        "release next week and then",
+			overlapSecs: 0,
+			want:        0,
+		},
+		{
+			name:        "empty previous chunk",
+			prev:        "",
+			next:        "release next week and then",
+			overlapSecs: 2,
+			want:        0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := overlapWordCount(tt.prev, tt.next, tt.overlapSecs); got != tt.want {
+				t.Errorf("overlapWordCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDropLeadingWords(t *testing.T) {
+	segments := []TranscriptSegment{
+		{Start: 0, End: 1, Text: "release next"},
+		{Start: 1, End: 2, Text: "week and then", Tokens: []TranscriptionToken{{Text: "week"}}},
+		{Start: 2, End: 3, Text: "start planning"},
+	}
+
+	got := dropLeadingWords(segments, 3)
+	var texts []string
+	for _, seg := range got {
+		texts = append(texts, seg.Text)
+	}
+	if want := "and then|start planning"; strings.Join(texts, "|") != want {
+		t.Errorf("dropLeadingWords() = %q, want %q", strings.Join(texts, "|"), want)
+	}
+	if got[0].Start != 1 || got[0].Tokens != nil {
+		t.Errorf("trimmed segment = %+v, want original timing and no tokens", got[0])
+	}
+}
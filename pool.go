@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+// transcriptionPool transcribes chunks on several workers at once. Results
+// are committed to the session strictly in the order the chunks were
+// submitted, so the transcript reads the same as with a single worker.
+type transcriptionPool struct {
+	t        *Transcriber
+	session  *Session
+	remove   bool                    // Remove chunk audio once committed
+	onError  func(AudioChunk, error) // Called in order for chunks that failed
+	jobs     chan poolJob
+	results  chan poolResult
+	slots    chan struct{} // Bounds chunks submitted but not yet committed
+	workers  sync.WaitGroup
+	done     chan struct{}
+	nextJob  int
+	finished bool
+}
+
+type poolJob struct {
+	seq   int
+	chunk AudioChunk
+}
+
+type poolResult struct {
+	poolJob
+	result *TranscriptionResult
+	silent bool // Dropped by voice detection before transcription
+	err    error
+}
+
+// newTranscriptionPool starts the configured number of workers for session
+func (t *Transcriber) newTranscriptionPool(session *Session, removeAudioFileOnSuccess bool, onError func(AudioChunk, error)) *transcriptionPool {
+	workers := t.config.TranscriptionWorkers
+	if workers < 1 {
+		workers = 1
+	}
+
+	p := &transcriptionPool{
+		t:       t,
+		session: session,
+		remove:  removeAudioFileOnSuccess,
+		onError: onError,
+		jobs:    make(chan poolJob),
+		results: make(chan poolResult, workers),
+		slots:   make(chan struct{}, 2*workers),
+		done:    make(chan struct{}),
+	}
+
+	p.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	go p.commit()
+	return p
+}
+
+// Submit queues chunk for transcription, blocking while too many chunks are
+// waiting to be committed. It must not be called after Close.
+func (p *transcriptionPool) Submit(chunk AudioChunk) {
+	p.slots <- struct{}{}
+	p.jobs <- poolJob{seq: p.nextJob, chunk: chunk}
+	p.nextJob++
+}
+
+// Close waits until every submitted chunk has been committed
+func (p *transcriptionPool) Close() {
+	if p.finished {
+		return
+	}
+	p.finished = true
+	close(p.jobs)
+	p.workers.Wait()
+	close(p.results)
+	<-p.done
+}
+
+func (p *transcriptionPool) work() {
+	defer p.workers.Done()
+	for job := range p.jobs {
+		silent, err := p.t.isSilentChunk(job.chunk)
+		if err != nil {
+			fmt.Printf("Warning: %v\n", err)
+		}
+		if silent {
+			p.results <- poolResult{poolJob: job, silent: true}
+			continue
+		}
+
+		result, err := p.t.transcribeChunkAudio(p.session, job.chunk)
+		p.results <- poolResult{poolJob: job, result: result, err: err}
+	}
+}
+
+// commit holds results that finished early until every chunk before them has
+// been committed
+func (p *transcriptionPool) commit() {
+	defer close(p.done)
+
+	pending := make(map[int]poolResult)
+	next := 0
+	for res := range p.results {
+		pending[res.seq] = res
+		for {
+			res, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+
+			if res.silent {
+				p.t.skipSilentChunk(p.session, res.chunk, p.remove)
+				<-p.slots
+				continue
+			}
+
+			err := res.err
+			if err == nil {
+				err = p.t.commitChunk(p.session, res.chunk, res.result, p.remove)
+			}
+			if err != nil && p.onError != nil {
+				p.onError(res.chunk, err)
+			}
+			<-p.slots
+		}
+	}
+}
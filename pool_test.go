@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reorderBackend finishes the chunks of each batch of workers in reverse
+// order, and fails the chunk numbered fail
+type reorderBackend struct {
+	workers   int
+	fail      int
+	committed func() int // Chunks committed or reported as failed so far
+
+	mu           sync.Mutex
+	started      int
+	running      int
+	maxRunning   int
+	maxUnsettled int
+	finished     []int
+}
+
+func (b *reorderBackend) Transcribe(audioFile, outputPath, prompt string) (*TranscriptionResult, error) {
+	var num int
+	fmt.Sscanf(filepath.Base(audioFile), "chunk_%d.wav", &num)
+
+	b.mu.Lock()
+	b.started++
+	b.running++
+	b.maxRunning = max(b.maxRunning, b.running)
+	b.maxUnsettled = max(b.maxUnsettled, b.started-b.committed())
+	b.mu.Unlock()
+
+	time.Sleep(time.Duration(b.workers-(num-1)%b.workers) * 5 * time.Millisecond)
+
+	b.mu.Lock()
+	b.running--
+	b.finished = append(b.finished, num)
+	b.mu.Unlock()
+
+	if num == b.fail {
+		return nil, fmt.Errorf("backend failed")
+	}
+	text := fmt.Sprintf("c%[1]d-one c%[1]d-two c%[1]d-three c%[1]d-four c%[1]d-five c%[1]d-six", num)
+	return &TranscriptionResult{Segments: []TranscriptSegment{{Start: 0, End: 10, Text: text}}}, nil
+}
+
+func TestPoolCommitsInOrder(t *testing.T) {
+	const workers, chunks = 3, 12
+
+	configDir := t.TempDir()
+	config := fmt.Sprintf(`{"temp_dir": %q, "transcription_workers": %d, "vad": "off"}`, t.TempDir(), workers)
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := NewTranscriber(configDir)
+	if err != nil {
+		t.Fatalf("NewTranscriber: %v", err)
+	}
+	tr.broadcaster = NewBroadcaster()
+
+	dir := t.TempDir()
+	session := newSession("20250101_120000", filepath.Join(dir, "run_20250101_120000"))
+
+	var mu sync.Mutex
+	var failed []int
+	committedBeforeFailure := -1
+	committed := func() int {
+		tr.broadcaster.mu.Lock()
+		defer tr.broadcaster.mu.Unlock()
+		mu.Lock()
+		defer mu.Unlock()
+		return len(tr.broadcaster.history[session.ID]) + len(failed)
+	}
+	backend := &reorderBackend{workers: workers, fail: 4, committed: committed}
+	tr.whisperService = &WhisperService{config: &tr.config, backend: backend}
+
+	pool := tr.newTranscriptionPool(session, false, func(chunk AudioChunk, err error) {
+		tr.broadcaster.mu.Lock()
+		n := len(tr.broadcaster.history[session.ID])
+		tr.broadcaster.mu.Unlock()
+		mu.Lock()
+		defer mu.Unlock()
+		failed = append(failed, chunk.Num)
+		committedBeforeFailure = n
+	})
+	for i := 1; i <= chunks; i++ {
+		path := filepath.Join(dir, fmt.Sprintf("chunk_%d.wav", i))
+		if err := os.WriteFile(path, []byte("not a wav"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		start := float64(i-1) * 10
+		pool.Submit(AudioChunk{Num: i, Path: path, Start: start, End: start + 10})
+	}
+	pool.Close()
+
+	if sort.IntsAreSorted(backend.finished) {
+		t.Fatalf("chunks finished in order %v; the test needs them out of order", backend.finished)
+	}
+
+	var order []int
+	for _, event := range tr.broadcaster.history[session.ID] {
+		order = append(order, event.Chunk)
+	}
+	want := []int{1, 2, 3, 5, 6, 7, 8, 9, 10, 11, 12}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("committed %v, want %v", order, want)
+	}
+	if !reflect.DeepEqual(failed, []int{4}) || committedBeforeFailure != 3 {
+		t.Errorf("failed %v after %d commits, want [4] after 3", failed, committedBeforeFailure)
+	}
+	if backend.maxRunning > workers {
+		t.Errorf("%d chunks transcribed at once, want at most %d", backend.maxRunning, workers)
+	}
+	if backend.maxUnsettled > 2*workers {
+		t.Errorf("%d chunks waiting to be committed, want at most %d", backend.maxUnsettled, 2*workers)
+	}
+}
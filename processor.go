@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Audio container extensions accepted by the process command
+var supportedAudioExtensions = map[string]bool{
+	".wav":  true,
+	".mp3":  true,
+	".m4a":  true,
+	".flac": true,
+	".ogg":  true,
+	".opus": true,
+	".aac":  true,
+	".webm": true,
+	".mp4":  true,
+}
+
+// findAudioFiles returns the audio files at inputPath. A file is returned as-is,
+// a directory is walked recursively.
+func findAudioFiles(inputPath string) ([]string, error) {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("input not accessible: %v", err)
+	}
+
+	if !info.IsDir() {
+		return []string{inputPath}, nil
+	}
+
+	var files []string
+	err = filepath.WalkDir(inputPath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if supportedAudioExtensions[strings.ToLower(filepath.Ext(path))] {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan input directory: %v", err)
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
+
+func (t *Transcriber) processFiles(inputPath, outputDir string) error {
+	if inputPath == "" {
+		inputPath = t.config.TempDir
+	}
+
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	files, err := findAudioFiles(inputPath)
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		fmt.Printf("No audio files found in %s\n", inputPath)
+		return nil
+	}
+
+	names, err := transcriptNames(inputPath, files)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Found %d audio file(s) to process\n", len(files))
+
+	failed := 0
+	for i, file := range files {
+		fmt.Printf("\n[%d/%d] Processing %s\n", i+1, len(files), file)
+		if err := t.processFile(file, filepath.Join(outputDir, names[file])); err != nil {
+			fmt.Printf("Error processing %s: %v\n", file, err)
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) failed", failed, len(files))
+	}
+	return nil
+}
+
+// transcriptNames maps each file to its transcript name: its path relative
+// to inputPath without the extension, so subdirectories are mirrored in the
+// output directory. Files that differ only by extension keep it, e.g.
+// x.mp3 and x.wav. Any names still shared are refused rather than letting
+// one transcript overwrite another.
+func transcriptNames(inputPath string, files []string) (map[string]string, error) {
+	rels := make(map[string]string)
+	stems := make(map[string]int)
+	for _, file := range files {
+		rel := filepath.Base(file)
+		if file != inputPath {
+			var err error
+			if rel, err = filepath.Rel(inputPath, file); err != nil {
+				return nil, fmt.Errorf("failed to name transcript for %s: %v", file, err)
+			}
+		}
+		rels[file] = rel
+		stems[strings.TrimSuffix(rel, filepath.Ext(rel))]++
+	}
+
+	names := make(map[string]string)
+	owners := make(map[string]string)
+	for _, file := range files {
+		name := strings.TrimSuffix(rels[file], filepath.Ext(rels[file]))
+		if stems[name] > 1 {
+			name = rels[file]
+		}
+		if other, ok := owners[name]; ok {
+			return nil, fmt.Errorf("%s and %s would both be transcribed to %s; rename one of them", other, file, name)
+		}
+		owners[name] = file
+		names[file] = name
+	}
+	return names, nil
+}
+
+// processFile transcribes inputFile to outputBase plus the output format's
+// extension
+func (t *Transcriber) processFile(inputFile, outputBase string) error {
+	if err := os.MkdirAll(filepath.Dir(outputBase), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	sessionID := time.Now().Format("20060102_150405")
+	session := newSession(sessionID, outputBase)
+	mainFile := session.MainFile(t.config.OutputFormat)
+
+	// Start from a clean transcript so re-processing doesn't append duplicates
+	if err := os.Remove(mainFile); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove previous transcript: %v", err)
+	}
+
+	if err := t.transcribeFile(session, inputFile); err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(mainFile); err == nil {
+		fmt.Printf("Transcription saved to: %s\n", mainFile)
+	}
+	return nil
+}
+
+// transcribeFile splits inputFile into chunks and appends their
+// transcriptions to session
+func (t *Transcriber) transcribeFile(session *Session, inputFile string) error {
+	baseName := strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(inputFile))
+	pattern := filepath.Join(escapePattern(t.config.TempDir),
+		fmt.Sprintf("process_%s_%s_%%05d.wav", escapePattern(session.ID), escapePattern(baseName)))
+
+	chunks, err := t.recorder.SplitFile(inputFile, pattern, t.config.ChunkDurationInSecs)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Split into %d chunk(s) of up to %d seconds\n", len(chunks), t.config.ChunkDurationInSecs)
+
+	// Chunk audio is our own temporary copy, so always clean it up
+	pool := t.newTranscriptionPool(session, true, func(chunk AudioChunk, err error) {
+		fmt.Printf("Error processing chunk %d: %v\n", chunk.Num, err)
+	})
+	defer pool.Close()
+
+	boundaries := newChunkBoundaries(&t.config)
+	overlapper := newChunkOverlapper(t.config.ChunkOverlapSecs)
+	submit := func(chunk AudioChunk) {
+		if err := overlapper.apply(&chunk); err != nil {
+			fmt.Printf("Warning: chunk %d processed without overlap: %v\n", chunk.Num, err)
+		}
+		pool.Submit(chunk)
+	}
+
+	for _, chunk := range chunks {
+		if info, err := os.Stat(chunk.Path); err != nil || info.Size() == 0 {
+			fmt.Printf("Warning: No valid audio for chunk %d, skipping\n", chunk.Num)
+			continue
+		}
+
+		if err := boundaries.apply(&chunk); err != nil {
+			fmt.Printf("Warning: chunk %d kept its split boundaries: %v\n", chunk.Num, err)
+		}
+		submit(chunk)
+	}
+
+	// Audio carried past the last pause becomes the final chunk
+	if chunk, ok, err := boundaries.flush(pattern); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	} else if ok {
+		submit(chunk)
+	}
+	return nil
+}
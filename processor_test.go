@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTranscriptNames(t *testing.T) {
+	in := filepath.Join("recordings", "in")
+	tests := []struct {
+		name    string
+		input   string
+		files   []string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:  "single file",
+			input: filepath.Join(in, "standup.m4a"),
+			files: []string{filepath.Join(in, "standup.m4a")},
+			want:  map[string]string{filepath.Join(in, "standup.m4a"): "standup"},
+		},
+		{
+			name:  "same name in different directories",
+			input: in,
+			files: []string{filepath.Join(in, "a", "meeting.wav"), filepath.Join(in, "b", "meeting.wav")},
+			want: map[string]string{
+				filepath.Join(in, "a", "meeting.wav"): filepath.Join("a", "meeting"),
+				filepath.Join(in, "b", "meeting.wav"): filepath.Join("b", "meeting"),
+			},
+		},
+		{
+			name:  "same name with different extensions",
+			input: in,
+			files: []string{filepath.Join(in, "x.mp3"), filepath.Join(in, "x.wav"), filepath.Join(in, "y.wav")},
+			want: map[string]string{
+				filepath.Join(in, "x.mp3"): "x.mp3",
+				filepath.Join(in, "x.wav"): "x.wav",
+				filepath.Join(in, "y.wav"): "y",
+			},
+		},
+		{
+			name:    "names still shared",
+			input:   in,
+			files:   []string{filepath.Join(in, "x.mp3"), filepath.Join(in, "x.mp3.ogg"), filepath.Join(in, "x.wav")},
+			wantErr: "would both be transcribed to x.mp3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := transcriptNames(tt.input, tt.files)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("transcriptNames: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEscapePattern(t *testing.T) {
+	dir := filepath.Join("tmp", "100%done")
+	pattern := filepath.Join(escapePattern(dir),
+		fmt.Sprintf("process_%s_%s_%%05d.wav", escapePattern("20250101_120000"), escapePattern("50%s off")))
+
+	want := filepath.Join(dir, "process_20250101_120000_50%s off_00003.wav")
+	if got := fmt.Sprintf(pattern, 3); got != want {
+		t.Errorf("expanded pattern = %q, want %q", got, want)
+	}
+
+	// ffmpeg writes the segment list to this path as given
+	wantList := filepath.Join(dir, "process_20250101_120000_50%s off_.csv")
+	if got := segmentListPath(pattern); got != wantList {
+		t.Errorf("segment list = %q, want %q", got, wantList)
+	}
+}
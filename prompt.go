@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PromptProfile is a named vocabulary and initial prompt, chosen with the
+// profile config key or --profile
+type PromptProfile struct {
+	Vocabulary    []string `json:"vocabulary"`
+	InitialPrompt string   `json:"initial_prompt"`
+}
+
+// basePrompt returns the initial prompt followed by the vocabulary, taken
+// from the active profile on top of the top-level settings
+func (t *Transcriber) basePrompt() string {
+	initialPrompt := t.config.InitialPrompt
+	vocabulary := t.config.Vocabulary
+	if profile, ok := t.config.Profiles[t.config.Profile]; ok {
+		if profile.InitialPrompt != "" {
+			initialPrompt = profile.InitialPrompt
+		}
+		vocabulary = append(append([]string(nil), vocabulary...), profile.Vocabulary...)
+	}
+
+	var parts []string
+	if text := strings.TrimSpace(initialPrompt); text != "" {
+		parts = append(parts, text)
+	}
+	if len(vocabulary) > 0 {
+		parts = append(parts, "Glossary: "+strings.Join(vocabulary, ", ")+".")
+	}
+	return strings.Join(parts, " ")
+}
+
+// chunkPrompt returns the prompt for a chunk: the base prompt plus, with
+// prompt_carry_words set, the end of the previous chunk's text so context
+// continues across the boundary
+func (t *Transcriber) chunkPrompt(session *Session, chunk AudioChunk) string {
+	prompt := t.basePrompt()
+	if t.config.PromptCarryWords <= 0 {
+		return prompt
+	}
+
+	tail := session.tailBefore(chunk.Num)
+	if tail == "" {
+		return prompt
+	}
+	if prompt == "" {
+		return tail
+	}
+	return prompt + " " + tail
+}
+
+// rememberTail keeps the last words of a chunk's text for the next chunk's
+// prompt. Chunks transcribed out of order only replace later text.
+func (s *Session) rememberTail(num int, text string, words int) {
+	fields := strings.Fields(text)
+	if len(fields) > words {
+		fields = fields[len(fields)-words:]
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if num > s.tailNum {
+		s.tailNum, s.tailText = num, strings.Join(fields, " ")
+	}
+}
+
+// tailBefore returns the remembered text of the chunk just before num, or ""
+// when that chunk has not been transcribed yet, as happens with several
+// transcription workers
+func (s *Session) tailBefore(num int) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tailNum != num-1 {
+		return ""
+	}
+	return s.tailText
+}
+
+// validateProfile checks that the profile config key names a profile
+func (t *Transcriber) validateProfile() error {
+	if t.config.Profile == "" {
+		return nil
+	}
+	if _, ok := t.config.Profiles[t.config.Profile]; !ok {
+		return fmt.Errorf("profile %q is not defined in profiles", t.config.Profile)
+	}
+	return nil
+}
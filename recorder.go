@@ -1,80 +1,211 @@
 package main
 
 import (
+	"bufio"
+	"encoding/csv"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
-	"os/signal"
+	"path/filepath"
+	"regexp"
 	"runtime"
-	"syscall"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 const MAX_RECORD_DURATION_IN_SECS = 30 * 60
 
+// AudioChunk is a completed segment of a continuous recording. Start and End
+// are offsets in seconds from the beginning of the recording. WindowStart is
+// the offset of the first sample in Path, which is earlier than Start when the
+// previous chunk's tail has been prepended as overlap.
+type AudioChunk struct {
+	Num         int
+	Path        string
+	Start       float64
+	End         float64
+	WindowStart float64
+}
+
+// StreamFormat describes raw PCM read from a stream input. An empty Format
+// lets ffmpeg detect the container, e.g. WAV or Ogg.
+type StreamFormat struct {
+	Format     string // ffmpeg raw format such as s16le
+	SampleRate int
+	Channels   int
+}
+
 type Recorder struct {
-	stopChan      chan struct{}
 	device        string
+	input         string // Stream to read instead of a device: "-" for stdin, a file or FIFO, or a URL
+	stream        StreamFormat
+	source        string // "mic" (default), "system", "mixed" or "dual"
+	monitor       string // Device capturing system output for system and mixed sources
 	displayOutput bool
+
+	// State of the long-lived capture process started by Start
+	mu            sync.Mutex
+	running       bool
+	cmd           *exec.Cmd
+	stdin         io.WriteCloser
+	exited        chan struct{}
+	stopRequested chan struct{}
+	waitErr       error
+	stopping      bool
 }
 
 func NewRecorder(device string, displayOutput bool) *Recorder {
 	return &Recorder{
-		stopChan:      make(chan struct{}),
 		device:        device,
 		displayOutput: displayOutput,
 	}
 }
 
+// NewStreamRecorder returns a recorder that reads audio from input instead of
+// a capture device. input is "-" for stdin, the path of a file or FIFO, or a
+// network stream URL.
+func NewStreamRecorder(input string, format StreamFormat, displayOutput bool) *Recorder {
+	return &Recorder{
+		input:         input,
+		stream:        format,
+		displayOutput: displayOutput,
+	}
+}
+
 func NewRecorderWithDefaultDevice(displayOutput bool) *Recorder {
 	device := getDefaultDevice()
 	return &Recorder{
-		stopChan:      make(chan struct{}),
 		device:        device,
 		displayOutput: displayOutput,
 	}
 }
 
-func (r *Recorder) getFFmpegCommand(outputFile string, duration int) *exec.Cmd {
-	if duration <= 0 {
-		duration = MAX_RECORD_DURATION_IN_SECS // Default to 10 seconds if no duration is specified
+// getInputArgs returns the ffmpeg arguments that select the capture source:
+// the input device, the monitor device, both mixed into one track, or both
+// side by side as the two channels of a stereo track
+func (r *Recorder) getInputArgs() []string {
+	if r.input != "" {
+		return r.streamInputArgs()
 	}
-	switch runtime.GOOS {
-	case "darwin": // macOS
-		return exec.Command("ffmpeg",
-			"-f", "avfoundation",
-			"-i", r.device,
-			"-t", fmt.Sprintf("%d", duration),
-			"-y",
-			outputFile,
-		)
-	case "linux":
-		return exec.Command("ffmpeg",
-			"-f", "alsa",
-			"-i", r.device,
-			"-t", fmt.Sprintf("%d", duration),
-			"-y",
-			outputFile,
-		)
-	case "windows":
-		return exec.Command("ffmpeg",
-			"-f", "dshow",
-			"-i", fmt.Sprintf("audio=%s", r.device),
-			"-t", fmt.Sprintf("%d", duration),
-			"-y",
-			outputFile,
-		)
+
+	switch r.source {
+	case "system":
+		return deviceInputArgs(r.monitor)
+	case "mixed":
+		args := append(deviceInputArgs(r.device), deviceInputArgs(r.monitor)...)
+		return append(args, "-filter_complex", "amix=inputs=2:duration=longest")
+	case "dual":
+		// Input device on the left channel, monitor on the right
+		args := append(deviceInputArgs(r.device), deviceInputArgs(r.monitor)...)
+		return append(args,
+			"-filter_complex", "[0:a]aformat=channel_layouts=mono[a0];[1:a]aformat=channel_layouts=mono[a1];[a0][a1]amerge=inputs=2[dual]",
+			"-map", "[dual]")
 	default:
-		// Fallback - try pulse for other Unix-like systems
-		return exec.Command("ffmpeg",
-			"-f", "pulse",
-			"-i", r.device,
-			"-t", fmt.Sprintf("%d", duration),
-			"-y",
-			outputFile,
-		)
+		return deviceInputArgs(r.device)
+	}
+}
+
+// streamInputArgs returns the ffmpeg arguments that read the input stream
+func (r *Recorder) streamInputArgs() []string {
+	var args []string
+	if r.stream.Format != "" {
+		args = append(args, "-f", r.stream.Format)
+		if r.stream.SampleRate > 0 {
+			args = append(args, "-ar", fmt.Sprintf("%d", r.stream.SampleRate))
+		}
+		if r.stream.Channels > 0 {
+			args = append(args, "-ac", fmt.Sprintf("%d", r.stream.Channels))
+		}
+	}
+
+	input := r.input
+	if input == "-" {
+		input = "pipe:0"
+	}
+	if r.isNetworkInput() {
+		if strings.HasPrefix(strings.ToLower(input), "rtsp") {
+			args = append(args, "-rtsp_transport", "tcp")
+		} else {
+			// Treat a stalled connection as dropped after 15s
+			args = append(args, "-rw_timeout", "15000000")
+		}
+	}
+	return append(args, "-i", input)
+}
+
+// isNetworkInput reports whether the recorder reads a network stream
+func (r *Recorder) isNetworkInput() bool {
+	return isNetworkURL(r.input)
+}
+
+// isNetworkURL reports whether input is a URL ffmpeg reads over the network,
+// such as an RTSP, HTTP, Icecast or SRT stream
+func isNetworkURL(input string) bool {
+	scheme, _, ok := strings.Cut(input, "://")
+	return ok && scheme != "" && !strings.EqualFold(scheme, "file")
+}
+
+// readsStdin reports whether the recorder takes its audio from stdin
+func (r *Recorder) readsStdin() bool {
+	return r.input == "-"
+}
+
+// deviceInputArgs returns the ffmpeg input for a device. The device may be
+// prefixed with its backend, e.g. "pulse:<source>".
+func deviceInputArgs(device string) []string {
+	backend, name := splitDeviceSpec(device)
+	if backend == "dshow" && !strings.HasPrefix(name, "audio=") {
+		name = "audio=" + name
+	}
+	return []string{"-f", backend, "-i", name}
+}
+
+// SetCaptureSource selects what the recorder captures: "mic" records the
+// input device, "system" the monitor device playing the system's output,
+// "mixed" both together and "dual" both in separate channels
+func (r *Recorder) SetCaptureSource(source, monitor string) {
+	r.source = source
+	r.monitor = monitor
+}
+
+// channels returns the number of channels in recorded chunks
+func (r *Recorder) channels() int {
+	if r.source == "dual" && r.input == "" {
+		return 2
+	}
+	return 1
+}
+
+// Device returns the capture device the recorder uses
+func (r *Recorder) Device() string {
+	return r.device
+}
+
+// getSegmentCommand builds a single capture process that writes consecutive
+// 16kHz mono WAV segments and reports each finished segment as a CSV line
+// (filename,start,end) on stdout.
+func (r *Recorder) getSegmentCommand(outputPattern string, segmentSecs, startNumber int, duration time.Duration) *exec.Cmd {
+	args := r.getInputArgs()
+	if duration > 0 {
+		args = append(args, "-t", fmt.Sprintf("%.3f", duration.Seconds()))
 	}
+	args = append(args,
+		"-ar", "16000",
+		"-ac", fmt.Sprintf("%d", r.channels()),
+		"-c:a", "pcm_s16le",
+		"-f", "segment",
+		"-segment_time", fmt.Sprintf("%d", segmentSecs),
+		"-segment_start_number", fmt.Sprintf("%d", startNumber),
+		"-segment_list", "pipe:1",
+		"-segment_list_type", "csv",
+		"-reset_timestamps", "1",
+		"-y",
+		outputPattern,
+	)
+	return exec.Command("ffmpeg", args...)
 }
 
 func (r *Recorder) isCleanExit(err error) bool {
@@ -99,77 +230,374 @@ func (r *Recorder) isCleanExit(err error) bool {
 	return false
 }
 
-func (r *Recorder) gracefulStop(cmd *exec.Cmd, stdin io.WriteCloser, done chan error) error {
-	fmt.Println("Stopping recording gracefully...")
+// escapePattern escapes % so s is taken literally in a segment output
+// pattern, which both ffmpeg and fmt.Sprintf expand. Segment lists name the
+// files unescaped.
+func escapePattern(s string) string {
+	return strings.ReplaceAll(s, "%", "%%")
+}
+
+// Escaped percent signs and the index verb in a segment output pattern
+var patternEscapes = regexp.MustCompile(`%%|%\d*d`)
+
+// segmentListPath returns the CSV segment list written alongside the
+// segments of outputPattern. ffmpeg takes the list path literally, so the
+// index verb is removed and escaped percent signs are restored.
+func segmentListPath(outputPattern string) string {
+	name := strings.TrimSuffix(outputPattern, filepath.Ext(outputPattern))
+	return patternEscapes.ReplaceAllStringFunc(name, func(m string) string {
+		if m == "%%" {
+			return "%"
+		}
+		return ""
+	}) + ".csv"
+}
 
-	// Send quit command to FFmpeg
-	if _, err := stdin.Write([]byte("q")); err != nil {
-		fmt.Printf("Warning: Could not send quit command: %v\n", err)
+// SplitFile decodes an existing audio file into 16kHz mono WAV segments of
+// segmentSecs each. outputPattern must contain a printf-style index (e.g. %03d).
+// The segments are returned in playback order with their offsets.
+func (r *Recorder) SplitFile(inputFile, outputPattern string, segmentSecs int) ([]AudioChunk, error) {
+	if segmentSecs <= 0 {
+		segmentSecs = MAX_RECORD_DURATION_IN_SECS
 	}
-	stdin.Close()
 
-	// Wait for FFmpeg to exit gracefully
-	time.Sleep(500 * time.Millisecond)
+	listFile := segmentListPath(outputPattern)
+	defer os.Remove(listFile)
 
-	select {
-	case err := <-done:
-		if r.isCleanExit(err) {
-			return nil
+	cmd := exec.Command("ffmpeg",
+		"-i", inputFile,
+		"-vn",
+		"-ar", "16000",
+		"-ac", "1",
+		"-c:a", "pcm_s16le",
+		"-f", "segment",
+		"-segment_time", fmt.Sprintf("%d", segmentSecs),
+		"-segment_start_number", "1",
+		"-segment_list", listFile,
+		"-segment_list_type", "csv",
+		"-reset_timestamps", "1",
+		"-y",
+		outputPattern,
+	)
+	if r.displayOutput {
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+	}
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to split %s: %v", inputFile, err)
+	}
+
+	f, err := os.Open(listFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read segment list: %v", err)
+	}
+	defer f.Close()
+
+	dir := filepath.Dir(strings.ReplaceAll(outputPattern, "%%", "%"))
+	var chunks []AudioChunk
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
 		}
-		return fmt.Errorf("FFmpeg error: %v", err)
-	case <-time.After(3 * time.Second):
-		fmt.Println("FFmpeg taking too long to exit, terminating...")
-		if cmd.Process != nil {
-			cmd.Process.Kill()
+		chunk, err := parseSegmentListEntry(scanner.Text(), dir)
+		if err != nil {
+			return nil, err
 		}
-		return fmt.Errorf("ffmpeg failed to exit cleanly")
+		chunk.Num = len(chunks) + 1
+		chunks = append(chunks, chunk)
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read segment list: %v", err)
+	}
+
+	return chunks, nil
 }
 
-func (r *Recorder) Record(outputFile string, duration int) error {
-	cmd := r.getFFmpegCommand(outputFile, duration)
+// Reconnect backoff for network inputs
+const (
+	reconnectInitialBackoff = time.Second
+	reconnectMaxBackoff     = 30 * time.Second
+	reconnectMaxFailures    = 10 // Consecutive attempts without audio before giving up
+)
+
+// Start launches one long-lived capture process that records continuously
+// into segments of segmentSecs, so no audio is lost between chunks. Segments
+// are numbered from startNumber. Completed segments are delivered on the
+// returned channel, which is closed once the process exits. A positive
+// duration bounds the recording; the last segment is shortened to fit. Call
+// Wait after the channel closes to get the result.
+//
+// Network inputs are reconnected with backoff when the stream drops. Chunk
+// numbering continues, and chunks recorded after a reconnect are placed on
+// the timeline by the time elapsed since Start, so outages show as gaps.
+func (r *Recorder) Start(outputPattern string, segmentSecs, startNumber int, duration time.Duration) (<-chan AudioChunk, error) {
+	if segmentSecs <= 0 {
+		segmentSecs = MAX_RECORD_DURATION_IN_SECS
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.running {
+		return nil, fmt.Errorf("recording already in progress")
+	}
 
-	// Set up stdout/stderr
+	if startNumber < 1 {
+		startNumber = 1
+	}
+
+	stdout, err := r.startSegmentProcessLocked(outputPattern, segmentSecs, startNumber, duration)
+	if err != nil {
+		return nil, err
+	}
+
+	r.running = true
+	r.exited = make(chan struct{})
+	r.stopRequested = make(chan struct{})
+	r.waitErr = nil
+	r.stopping = false
+
+	chunks := make(chan AudioChunk, 4)
+	go r.capture(stdout, chunks, outputPattern, segmentSecs, startNumber, duration)
+	return chunks, nil
+}
+
+// startSegmentProcessLocked starts a capture process and returns its segment
+// list output. r.mu must be held.
+func (r *Recorder) startSegmentProcessLocked(outputPattern string, segmentSecs, startNumber int, duration time.Duration) (io.Reader, error) {
+	cmd := r.getSegmentCommand(outputPattern, segmentSecs, startNumber, duration)
 	if r.displayOutput {
-		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 	}
-	stdin, err := cmd.StdinPipe()
+	stdout, err := cmd.StdoutPipe()
 	if err != nil {
-		return fmt.Errorf("failed to create stdin pipe: %v", err)
+		return nil, fmt.Errorf("failed to create stdout pipe: %v", err)
+	}
+
+	// ffmpeg's stdin is either the audio itself or our channel for "q"
+	var stdin io.WriteCloser
+	if r.readsStdin() {
+		cmd.Stdin = os.Stdin
+	} else if stdin, err = cmd.StdinPipe(); err != nil {
+		return nil, fmt.Errorf("failed to create stdin pipe: %v", err)
 	}
 
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start recording: %v", err)
+		return nil, fmt.Errorf("failed to start recording: %v", err)
 	}
 
-	// Monitor process completion
-	done := make(chan error, 1)
-	go func() {
-		done <- cmd.Wait()
-	}()
+	r.cmd = cmd
+	r.stdin = stdin
+	return stdout, nil
+}
+
+// capture delivers the segments of the capture process started by Start,
+// reconnecting network inputs until stopped or duration has been recorded
+func (r *Recorder) capture(stdout io.Reader, chunks chan<- AudioChunk, outputPattern string, segmentSecs, startNumber int, duration time.Duration) {
+	defer close(chunks)
 
-	// Set up signal handling
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
-	defer signal.Stop(sigChan)
+	dir := filepath.Dir(strings.ReplaceAll(outputPattern, "%%", "%"))
+	num := startNumber - 1
+	started := time.Now()
+	offset, end := 0.0, 0.0
+	failures := 0
+	backoff := reconnectInitialBackoff
 
-	select {
-	case sig := <-sigChan:
-		fmt.Printf("\nReceived signal: %v\n", sig)
-		return r.gracefulStop(cmd, stdin, done)
-	case err := <-done:
-		if r.isCleanExit(err) {
-			return nil
+	var err error
+	for {
+		delivered := false
+		if stdout != nil {
+			scanner := bufio.NewScanner(stdout)
+			for scanner.Scan() {
+				chunk, err := parseSegmentListEntry(scanner.Text(), dir)
+				if err != nil {
+					fmt.Printf("Warning: %v\n", err)
+					continue
+				}
+				num++
+				chunk.Num = num
+				chunk.Start += offset
+				chunk.End += offset
+				end = chunk.End
+				delivered = true
+				chunks <- chunk
+			}
+
+			r.mu.Lock()
+			cmd := r.cmd
+			r.mu.Unlock()
+			err = cmd.Wait()
+		}
+
+		r.mu.Lock()
+		r.cmd = nil
+		r.stdin = nil
+		stopping := r.stopping
+		r.mu.Unlock()
+
+		if !r.isNetworkInput() || stopping {
+			break
+		}
+		if duration > 0 && end >= duration.Seconds()-0.5 {
+			break
+		}
+
+		if delivered {
+			failures = 0
+			backoff = reconnectInitialBackoff
+		}
+		failures++
+		if failures > reconnectMaxFailures {
+			err = fmt.Errorf("giving up on %s after %d reconnect attempts: %v", r.input, reconnectMaxFailures, err)
+			break
+		}
+
+		reason := "stream ended"
+		if err != nil {
+			reason = err.Error()
+		}
+		fmt.Printf("Stream %s dropped (%s); reconnecting in %v (attempt %d of %d)\n",
+			r.input, reason, backoff, failures, reconnectMaxFailures)
+		select {
+		case <-time.After(backoff):
+		case <-r.stopRequested:
+		}
+		backoff *= 2
+		if backoff > reconnectMaxBackoff {
+			backoff = reconnectMaxBackoff
+		}
+
+		// Continue the timeline at the current wall-clock position
+		offset = time.Since(started).Seconds()
+		if end > offset {
+			offset = end
+		}
+		var remaining time.Duration
+		if duration > 0 {
+			remaining = duration - time.Duration(offset*float64(time.Second))
+			if remaining <= 0 {
+				err = nil
+				break
+			}
+		}
+
+		r.mu.Lock()
+		if r.stopping {
+			r.mu.Unlock()
+			err = nil
+			break
+		}
+		stdout, err = r.startSegmentProcessLocked(outputPattern, segmentSecs, num+1, remaining)
+		r.mu.Unlock()
+		if err != nil {
+			stdout = nil
 		}
-		return fmt.Errorf("recording failed: %v", err)
 	}
+
+	r.mu.Lock()
+	r.waitErr = err
+	r.running = false
+	r.mu.Unlock()
+	close(r.exited)
+}
+
+// parseSegmentListEntry parses one "filename,start,end" line of ffmpeg's CSV
+// segment list. Filenames are relative to the segment directory.
+func parseSegmentListEntry(line, dir string) (AudioChunk, error) {
+	record, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil || len(record) < 3 {
+		return AudioChunk{}, fmt.Errorf("malformed segment entry %q", line)
+	}
+
+	start, err := strconv.ParseFloat(record[1], 64)
+	if err != nil {
+		return AudioChunk{}, fmt.Errorf("malformed segment start %q", record[1])
+	}
+	end, err := strconv.ParseFloat(record[2], 64)
+	if err != nil {
+		return AudioChunk{}, fmt.Errorf("malformed segment end %q", record[2])
+	}
+
+	path := record[0]
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	return AudioChunk{Path: path, Start: start, End: end}, nil
 }
 
+// Stop asks the capture process started by Start to finish the current
+// segment and exit. It does not wait; drain the chunk channel and call Wait.
 func (r *Recorder) Stop() {
-	// Implementation depends on your recorder - this should interrupt the ongoing recording
-	// For example, if using a process, send a signal to stop it
-	// This is a placeholder - implement based on your actual recorder implementation
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.running || r.stopping {
+		return
+	}
+	r.stopping = true
+	close(r.stopRequested)
+
+	// Between reconnects there is no process to stop
+	if r.cmd == nil {
+		return
+	}
+
+	fmt.Println("Stopping recording gracefully...")
+
+	// Send quit command to FFmpeg. It may already be gone if it received the
+	// same interrupt from the terminal.
+	if r.stdin != nil {
+		if _, err := r.stdin.Write([]byte("q")); err != nil {
+			fmt.Printf("Warning: Could not send quit command: %v\n", err)
+		}
+		r.stdin.Close()
+	} else if err := r.cmd.Process.Signal(os.Interrupt); err != nil {
+		// stdin carries the audio; interrupt ffmpeg instead, or on
+		// platforms without signals let the fallback below kill it
+		fmt.Printf("Warning: Could not interrupt ffmpeg: %v\n", err)
+	}
+
+	cmd := r.cmd
+	exited := r.exited
+	go func() {
+		select {
+		case <-exited:
+		case <-time.After(5 * time.Second):
+			fmt.Println("FFmpeg taking too long to exit, terminating...")
+			if cmd.Process != nil {
+				cmd.Process.Kill()
+			}
+		}
+	}()
+}
+
+// StopRequested reports whether Stop was called for the current or most
+// recent capture process
+func (r *Recorder) StopRequested() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.stopping
+}
+
+// Wait blocks until the capture process started by Start exits.
+func (r *Recorder) Wait() error {
+	r.mu.Lock()
+	exited := r.exited
+	r.mu.Unlock()
+
+	if exited == nil {
+		return nil
+	}
+	<-exited
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.isCleanExit(r.waitErr) {
+		return nil
+	}
+	return fmt.Errorf("recording failed: %v", r.waitErr)
 }
 
 func getDefaultDevice() string {
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeStreamFFmpeg puts an ffmpeg script first on PATH that reports two
+// segments and then drops on its first run, and reports one more segment and
+// exits cleanly on later runs. Each run's arguments are saved as argsN.
+const fakeStreamFFmpeg = `#!/bin/sh
+dir=$(dirname "$0")
+n=$(cat "$dir/runs" 2>/dev/null || echo 0)
+n=$((n+1))
+echo $n > "$dir/runs"
+echo "$@" > "$dir/args$n"
+if [ $n = 1 ]; then
+	printf 'chunk_00001.wav,0.000000,2.000000\nchunk_00002.wav,2.000000,4.000000\n'
+	exit 1
+fi
+printf 'chunk_00003.wav,0.000000,2.000000\n'
+`
+
+func TestRecorderReconnectsNetworkStream(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte(fakeStreamFFmpeg), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dir := t.TempDir()
+	r := NewStreamRecorder("http://radio.example/live", StreamFormat{}, false)
+	chunks, err := r.Start(filepath.Join(dir, "chunk_%05d.wav"), 2, 1, 6*time.Second)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	var got []AudioChunk
+	for chunk := range chunks {
+		got = append(got, chunk)
+	}
+	if err := r.Wait(); err != nil {
+		t.Errorf("Wait: %v", err)
+	}
+
+	// The reconnect comes about a second after the drop, well before the 4s
+	// already recorded, so the timeline continues from the last chunk's end
+	want := []AudioChunk{
+		{Num: 1, Path: filepath.Join(dir, "chunk_00001.wav"), Start: 0, End: 2},
+		{Num: 2, Path: filepath.Join(dir, "chunk_00002.wav"), Start: 2, End: 4},
+		{Num: 3, Path: filepath.Join(dir, "chunk_00003.wav"), Start: 4, End: 6},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d chunks, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("chunk %d = %+v, want %+v", i+1, got[i], want[i])
+		}
+	}
+
+	// The second process continues the numbering and records what remains
+	first, err := os.ReadFile(filepath.Join(bin, "args1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := os.ReadFile(filepath.Join(bin, "args2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, arg := range []string{"-i http://radio.example/live", "-t 6.000", "-segment_start_number 1"} {
+		if !strings.Contains(string(first), arg) {
+			t.Errorf("first run args %q missing %q", first, arg)
+		}
+	}
+	for _, arg := range []string{"-t 2.000", "-segment_start_number 3"} {
+		if !strings.Contains(string(second), arg) {
+			t.Errorf("reconnect args %q missing %q", second, arg)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(bin, "args3")); err == nil {
+		t.Error("recorder reconnected after the duration was recorded")
+	}
+}
+
+func TestRecorderDoesNotReconnectStdin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte(fakeStreamFFmpeg), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dir := t.TempDir()
+	r := NewStreamRecorder("-", StreamFormat{Format: "s16le", SampleRate: 16000, Channels: 1}, false)
+	chunks, err := r.Start(filepath.Join(dir, "chunk_%05d.wav"), 2, 1, 0)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	var got []AudioChunk
+	for chunk := range chunks {
+		got = append(got, chunk)
+	}
+	if len(got) != 2 || got[1].Start != 2 || got[1].End != 4 {
+		t.Errorf("unexpected chunks: %+v", got)
+	}
+	if err := r.Wait(); err == nil {
+		t.Error("expected the failed capture to be reported")
+	}
+
+	args, err := os.ReadFile(filepath.Join(bin, "args1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(args), "-f s16le -ar 16000 -ac 1 -i pipe:0") {
+		t.Errorf("args %q should read raw PCM from stdin", args)
+	}
+	if _, err := os.Stat(filepath.Join(bin, "args2")); err == nil {
+		t.Error("stdin input should not be reconnected")
+	}
+}
+
+// fakeSplitFFmpeg writes a two-segment list to the -segment_list path it is
+// given, failing as ffmpeg would when that path cannot be created
+const fakeSplitFFmpeg = `#!/bin/sh
+while [ $# -gt 0 ]; do
+	if [ "$1" = "-segment_list" ]; then
+		list="$2"
+	fi
+	shift
+done
+printf 'process_1_00001.wav,0.000000,30.000000\nprocess_1_00002.wav,30.000000,41.500000\n' > "$list" || exit 1
+`
+
+func TestSplitFileInPercentDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte(fakeSplitFFmpeg), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	dir := filepath.Join(t.TempDir(), "100%done")
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	pattern := filepath.Join(escapePattern(dir), "process_1_%05d.wav")
+
+	chunks, err := NewRecorder("", false).SplitFile("meeting.mp3", pattern, 30)
+	if err != nil {
+		t.Fatalf("SplitFile: %v", err)
+	}
+	want := []AudioChunk{
+		{Num: 1, Path: filepath.Join(dir, "process_1_00001.wav"), Start: 0, End: 30},
+		{Num: 2, Path: filepath.Join(dir, "process_1_00002.wav"), Start: 30, End: 41.5},
+	}
+	if len(chunks) != len(want) || chunks[0] != want[0] || chunks[1] != want[1] {
+		t.Errorf("chunks = %+v, want %+v", chunks, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "process_1_.csv")); !os.IsNotExist(err) {
+		t.Error("segment list should be removed after reading")
+	}
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// PII types redact_types may list
+var supportedRedactionTypes = map[string]bool{
+	"email":       true,
+	"phone":       true,
+	"card":        true,
+	"national_id": true,
+}
+
+// Locales redact_locales may list, for phone and national ID formats
+var supportedRedactionLocales = map[string]bool{
+	"us": true,
+	"gb": true,
+	"in": true,
+}
+
+// redactionRule finds one kind of PII. valid, when set, rejects matches that
+// only look like it.
+type redactionRule struct {
+	kind  string
+	re    *regexp.Regexp
+	valid func(string) bool
+}
+
+// Patterns by type and locale. Rules run in this order, so card numbers are
+// claimed before the phone patterns see their digits.
+var (
+	emailPattern = regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)
+	cardPattern  = regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`)
+
+	nationalIDPatterns = map[string][]*regexp.Regexp{
+		"us": {regexp.MustCompile(`\b\d{3}[- ]\d{2}[- ]\d{4}\b`)},                          // SSN
+		"gb": {regexp.MustCompile(`\b[A-CEGHJ-PR-TW-Z]{2} ?\d{2} ?\d{2} ?\d{2} ?[A-D]\b`)}, // National Insurance number
+		"in": {
+			regexp.MustCompile(`\b[2-9]\d{3} ?\d{4} ?\d{4}\b`), // Aadhaar
+			regexp.MustCompile(`\b[A-Z]{5}\d{4}[A-Z]\b`),       // PAN
+		},
+	}
+
+	// International numbers with a country code are caught whatever the locale
+	internationalPhonePattern = regexp.MustCompile(`\+\d{1,3}[ .-]?\(?\d{1,4}\)?(?:[ .-]?\d){5,12}\b`)
+	phonePatterns             = map[string]*regexp.Regexp{
+		"us": regexp.MustCompile(`(?:\b1[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]?\d{4}\b`),
+		"gb": regexp.MustCompile(`\b0\d{2,4}[ ]?\d{3,4}[ ]?\d{3,4}\b`),
+		"in": regexp.MustCompile(`\b0?[6-9]\d{4}[ -]?\d{5}\b`),
+	}
+)
+
+// redaction maps one placeholder to the value it replaced
+type redaction struct {
+	Placeholder string `json:"placeholder"`
+	Type        string `json:"type"`
+	Value       string `json:"value"`
+	Chunk       int    `json:"chunk"`
+}
+
+// redactor replaces PII in transcript text with typed placeholders such as
+// [EMAIL_1] and, with a key file, records the originals in an encrypted
+// side-file next to the transcript
+type redactor struct {
+	rules   []redactionRule
+	keyPath string
+	key     []byte
+}
+
+// newRedactor returns nil when redaction is off
+func newRedactor(config *Config) (*redactor, error) {
+	if !config.RedactPII {
+		return nil, nil
+	}
+
+	types := make(map[string]bool)
+	for _, kind := range config.RedactTypes {
+		kind = strings.ToLower(kind)
+		if !supportedRedactionTypes[kind] {
+			return nil, fmt.Errorf("unsupported redact_types entry %q (use email, phone, card or national_id)", kind)
+		}
+		types[kind] = true
+	}
+	var locales []string
+	for _, locale := range config.RedactLocales {
+		locale = strings.ToLower(locale)
+		if !supportedRedactionLocales[locale] {
+			return nil, fmt.Errorf("unsupported redact_locales entry %q (use us, gb or in)", locale)
+		}
+		locales = append(locales, locale)
+	}
+
+	r := &redactor{keyPath: config.RedactionKeyFile}
+	if r.keyPath != "" {
+		// Loaded once here; chunks of concurrent sessions share the redactor
+		key, err := loadRedactionKey(r.keyPath, true)
+		if err != nil {
+			return nil, err
+		}
+		r.key = key
+	}
+	if types["email"] {
+		r.rules = append(r.rules, redactionRule{kind: "email", re: emailPattern})
+	}
+	if types["card"] {
+		r.rules = append(r.rules, redactionRule{kind: "card", re: cardPattern, valid: luhnValid})
+	}
+	if types["national_id"] {
+		for _, locale := range locales {
+			for _, re := range nationalIDPatterns[locale] {
+				r.rules = append(r.rules, redactionRule{kind: "national_id", re: re})
+			}
+		}
+	}
+	if types["phone"] {
+		r.rules = append(r.rules, redactionRule{kind: "phone", re: internationalPhonePattern})
+		for _, locale := range locales {
+			r.rules = append(r.rules, redactionRule{kind: "phone", re: phonePatterns[locale]})
+		}
+	}
+	return r, nil
+}
+
+// luhnValid reports whether the digits in s form a card number with a valid
+// Luhn check digit
+func luhnValid(s string) bool {
+	var digits []int
+	for _, c := range s {
+		if c >= '0' && c <= '9' {
+			digits = append(digits, int(c-'0'))
+		}
+	}
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+
+	sum := 0
+	for i := range digits {
+		d := digits[len(digits)-1-i]
+		if i%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+	}
+	return sum%10 == 0
+}
+
+// redact replaces PII in text, using place to choose the placeholder for
+// each match
+func (r *redactor) redact(text string, place func(kind, value string) string) string {
+	for _, rule := range r.rules {
+		text = rule.re.ReplaceAllStringFunc(text, func(match string) string {
+			if rule.valid != nil && !rule.valid(match) {
+				return match
+			}
+			return place(rule.kind, match)
+		})
+	}
+	return text
+}
+
+// mask replaces PII in text with unnumbered placeholders, for logs that are
+// not mapped back
+func (r *redactor) mask(text string) string {
+	if r == nil {
+		return text
+	}
+	return r.redact(text, func(kind, value string) string {
+		return "[" + strings.ToUpper(kind) + "]"
+	})
+}
+
+// redactSegments replaces PII in a chunk's segments. Each speaker's segments
+// are redacted as one line, so a number whisper split over two segments is
+// still caught. The same value gets the same placeholder throughout the
+// session. Chunks must be redacted one at a time, in order.
+func (r *redactor) redactSegments(session *Session, chunk AudioChunk, segments []TranscriptSegment) []TranscriptSegment {
+	if session.placeholders == nil {
+		r.loadRedactions(session)
+	}
+
+	var added []redaction
+	place := func(kind, value string) string {
+		id := kind + "\x00" + strings.ToLower(value)
+		if placeholder, ok := session.placeholders[id]; ok {
+			return placeholder
+		}
+		session.redactionCounts[kind]++
+		placeholder := fmt.Sprintf("[%s_%d]", strings.ToUpper(kind), session.redactionCounts[kind])
+		session.placeholders[id] = placeholder
+		added = append(added, redaction{Placeholder: placeholder, Type: kind, Value: value, Chunk: chunk.Num})
+		return placeholder
+	}
+
+	segments = mapLines(segments, func(line TranscriptSegment) (TranscriptSegment, bool) {
+		line.Text = r.redact(line.Text, place)
+		return line, true
+	})
+
+	if len(added) > 0 {
+		fmt.Printf("Redacted %d value(s) in chunk %d\n", len(added), chunk.Num)
+		if err := r.appendRedactions(session.RedactionsPath(), added); err != nil {
+			fmt.Printf("Warning: failed to record redactions: %v\n", err)
+		}
+	}
+	return segments
+}
+
+// loadRedactions picks up the placeholders of a resumed session from its
+// side-file, so numbering continues and repeated values keep their
+// placeholder
+func (r *redactor) loadRedactions(session *Session) {
+	session.placeholders = make(map[string]string)
+	session.redactionCounts = make(map[string]int)
+	if r.keyPath == "" {
+		return
+	}
+	if _, err := os.Stat(session.RedactionsPath()); err != nil {
+		return
+	}
+
+	existing, err := r.readRedactions(session.RedactionsPath())
+	if err != nil {
+		fmt.Printf("Warning: failed to read existing redactions: %v\n", err)
+		return
+	}
+	for _, entry := range existing {
+		session.placeholders[entry.Type+"\x00"+strings.ToLower(entry.Value)] = entry.Placeholder
+		session.redactionCounts[entry.Type]++
+	}
+}
+
+// RedactionsPath returns the encrypted side-file mapping placeholders back
+// to the values they replaced
+func (s *Session) RedactionsPath() string {
+	return s.OutputPath + ".redactions.enc"
+}
+
+// loadRedactionKey reads the AES-256 key, stored hex-encoded in the key file.
+// With create set, a missing file is created with a new random key.
+func loadRedactionKey(path string, create bool) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) && create {
+		key := make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+			return nil, fmt.Errorf("failed to create redaction key: %v", err)
+		}
+		fmt.Printf("Created redaction key %s; keep it safe, it is needed to reveal redacted values\n", path)
+		return key, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read redaction key: %v", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("redaction key %s must hold 32 hex-encoded bytes", path)
+	}
+	return key, nil
+}
+
+func (r *redactor) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(r.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// appendRedactions encrypts each entry with AES-GCM and appends it to the
+// side-file as one base64 line. Nothing is recorded without a key file.
+func (r *redactor) appendRedactions(path string, entries []redaction) error {
+	if r.keyPath == "" {
+		return nil
+	}
+	aead, err := r.cipher()
+	if err != nil {
+		return err
+	}
+
+	var buf strings.Builder
+	for _, entry := range entries {
+		plain, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return err
+		}
+		sealed := aead.Seal(nonce, nonce, plain, nil)
+		buf.WriteString(base64.StdEncoding.EncodeToString(sealed) + "\n")
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(buf.String())
+	return err
+}
+
+// readRedactions decrypts a side-file written by appendRedactions
+func (r *redactor) readRedactions(path string) ([]redaction, error) {
+	aead, err := r.cipher()
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []redaction
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		sealed, err := base64.StdEncoding.DecodeString(scanner.Text())
+		if err != nil || len(sealed) < aead.NonceSize() {
+			return nil, fmt.Errorf("%s line %d is corrupt", path, line)
+		}
+		plain, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d could not be decrypted; wrong key?", path, line)
+		}
+		var entry redaction
+		if err := json.Unmarshal(plain, &entry); err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNewRedactorLoadsKeyOnce(t *testing.T) {
+	dir := t.TempDir()
+	config := &Config{
+		RedactPII:        true,
+		RedactTypes:      []string{"email"},
+		RedactionKeyFile: filepath.Join(dir, "keys", "redaction.key"),
+	}
+
+	first, err := newRedactor(config)
+	if err != nil {
+		t.Fatalf("newRedactor: %v", err)
+	}
+	info, err := os.Stat(config.RedactionKeyFile)
+	if err != nil {
+		t.Fatalf("key file not created: %v", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0600 {
+		t.Errorf("key file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	second, err := newRedactor(config)
+	if err != nil {
+		t.Fatalf("newRedactor with existing key: %v", err)
+	}
+	if len(first.key) != 32 || !bytes.Equal(first.key, second.key) {
+		t.Fatal("second redactor should load the key the first one created")
+	}
+
+	// Sessions redacting at the same time share the loaded key
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := filepath.Join(dir, "session"+string(rune('a'+i))+".redactions.enc")
+			entry := redaction{Placeholder: "[EMAIL_1]", Type: "email", Value: "a@example.com", Chunk: i}
+			if err := first.appendRedactions(path, []redaction{entry}); err != nil {
+				t.Errorf("appendRedactions: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	entries, err := second.readRedactions(filepath.Join(dir, "sessionc.redactions.enc"))
+	if err != nil {
+		t.Fatalf("readRedactions: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Value != "a@example.com" || entries[0].Chunk != 2 {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+}
+
+func TestLoadRedactionKeyErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	missing := filepath.Join(dir, "missing.key")
+	if _, err := loadRedactionKey(missing, false); err == nil {
+		t.Error("expected an error for a missing key without create")
+	}
+	if _, err := os.Stat(missing); err == nil {
+		t.Error("key file should not be created without create")
+	}
+
+	short := filepath.Join(dir, "short.key")
+	if err := os.WriteFile(short, []byte("abcd\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadRedactionKey(short, true); err == nil || !strings.Contains(err.Error(), "32 hex-encoded bytes") {
+		t.Errorf("error = %v, want a key length error", err)
+	}
+}
+
+func TestRedactSegments(t *testing.T) {
+	dir := t.TempDir()
+	r, err := newRedactor(&Config{
+		RedactPII:        true,
+		RedactTypes:      []string{"email", "phone", "card"},
+		RedactLocales:    []string{"us"},
+		RedactionKeyFile: filepath.Join(dir, "redaction.key"),
+	})
+	if err != nil {
+		t.Fatalf("newRedactor: %v", err)
+	}
+
+	session := newSession("20250101_120000", filepath.Join(dir, "run_20250101_120000"))
+	segments := r.redactSegments(session, AudioChunk{Num: 1}, []TranscriptSegment{
+		{Text: "Mail jane@example.com or call (555) 123-4567."},
+		{Text: "Again, JANE@example.com, card 4111 1111 1111 1111, not 4111 1111 1111 1112.",
+			Tokens: []TranscriptionToken{{Text: "jane", P: 0.9}}},
+	})
+
+	want := []string{
+		"Mail [EMAIL_1] or call [PHONE_1].",
+		"Again, [EMAIL_1], card [CARD_1], not 4111 1111 1111 1112.",
+	}
+	for i, seg := range segments {
+		if seg.Text != want[i] {
+			t.Errorf("segment %d = %q, want %q", i, seg.Text, want[i])
+		}
+	}
+	if segments[1].Tokens != nil {
+		t.Error("tokens of a redacted segment should be dropped")
+	}
+
+	entries, err := r.readRedactions(session.RedactionsPath())
+	if err != nil {
+		t.Fatalf("readRedactions: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d recorded values, want 3: %+v", len(entries), entries)
+	}
+
+	// A resumed session continues the numbering from the side-file
+	resumed := newSession(session.ID, session.OutputPath)
+	segments = r.redactSegments(resumed, AudioChunk{Num: 2}, []TranscriptSegment{{Text: "jane@example.com and bob@example.com"}})
+	if got := segments[0].Text; got != "[EMAIL_1] and [EMAIL_2]" {
+		t.Errorf("resumed segment = %q, want %q", got, "[EMAIL_1] and [EMAIL_2]")
+	}
+}
+
+func TestRedactNumberSplitAcrossSegments(t *testing.T) {
+	dir := t.TempDir()
+	r, err := newRedactor(&Config{
+		RedactPII:        true,
+		RedactTypes:      []string{"phone"},
+		RedactLocales:    []string{"us"},
+		RedactionKeyFile: filepath.Join(dir, "redaction.key"),
+	})
+	if err != nil {
+		t.Fatalf("newRedactor: %v", err)
+	}
+	session := newSession("20250101_120000", filepath.Join(dir, "run_20250101_120000"))
+
+	// whisper.max_len, or whisper's own segmenting, can split a number
+	segments := r.redactSegments(session, AudioChunk{Num: 1}, []TranscriptSegment{
+		{Start: 0, End: 2, Text: " Call me at 555"},
+		{Start: 2, End: 4, Text: " 123 4567 tomorrow."},
+		{Start: 4, End: 6, Text: " Or 555"},
+		{Start: 6, End: 7, Text: " 987-6543."},
+	})
+	want := []TranscriptSegment{
+		{Start: 0, End: 2, Text: "Call me at [PHONE_1]"},
+		{Start: 2, End: 4, Text: "tomorrow."},
+		{Start: 4, End: 7, Text: "Or [PHONE_2]."},
+	}
+	if !reflect.DeepEqual(segments, want) {
+		t.Errorf("got %+v, want %+v", segments, want)
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// queueRetry puts a chunk whose transcription failed on the session's
+// on-disk retry queue. Its audio stays in the temp directory until it is
+// transcribed.
+func (t *Transcriber) queueRetry(session *Session, chunk AudioChunk, err error) {
+	// The next chunk must not be stitched against text from before the hole
+	session.lastText, session.lastNum = "", 0
+
+	backoff := time.Duration(t.config.RetryBackoffSecs) * time.Second
+	session.chunkFailed(chunk.Num, err, backoff, t.config.RetryMaxAttempts)
+}
+
+// retryDueChunks submits the queued chunks whose backoff has elapsed to pool.
+// Failures come back through the pool's error handler.
+func (t *Transcriber) retryDueChunks(session *Session, pool *transcriptionPool) {
+	for _, chunk := range session.takeDueRetries(time.Now()) {
+		fmt.Printf("Retrying chunk %d\n", chunk.Num)
+		pool.Submit(chunk)
+	}
+}
+
+// retrySession re-runs every chunk of a session still pending or queued for
+// retry. Chunks that land after later ones are put back in order.
+func (t *Transcriber) retrySession(sessionRef, outputDir string) error {
+	manifestPath, err := findSessionManifest(sessionRef, outputDir)
+	if err != nil {
+		return err
+	}
+
+	session, err := loadSession(manifestPath, t.config.TempDir)
+	if err != nil {
+		return err
+	}
+	if session.outputFormat != "" && session.outputFormat != t.config.OutputFormat {
+		return fmt.Errorf("session %s was recorded as %s but output_format is %s",
+			session.ID, session.outputFormat, t.config.OutputFormat)
+	}
+
+	outstanding := session.outstandingChunks()
+	if len(outstanding) == 0 {
+		fmt.Printf("Session %s has no outstanding chunks.\n", session.ID)
+		return nil
+	}
+
+	fmt.Printf("Retrying %d outstanding chunk(s) of session %s\n", len(outstanding), session.ID)
+
+	failed := 0
+	pool := t.newTranscriptionPool(session, true, func(chunk AudioChunk, err error) {
+		fmt.Printf("Error processing chunk %d: %v\n", chunk.Num, err)
+		session.chunkFailed(chunk.Num, err, 0, 0)
+		failed++
+	})
+	for _, chunk := range outstanding {
+		if _, err := os.Stat(chunk.Path); err != nil {
+			fmt.Printf("Audio for chunk %d is missing (%s), skipping\n", chunk.Num, chunk.Path)
+			session.chunkFinished(chunk.Num, chunkSkipped)
+			continue
+		}
+		pool.Submit(chunk)
+	}
+	pool.Close()
+
+	fmt.Printf("Transcription saved to: %s\n", session.MainFile(t.config.OutputFormat))
+
+	if failed > 0 {
+		return fmt.Errorf("%d chunk(s) still failing", failed)
+	}
+	return nil
+}
+
+// rebuildTranscript rewrites the session's transcript from its journal in
+// chunk order
+func (t *Transcriber) rebuildTranscript(session *Session) error {
+	entries, err := session.readJournal()
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	mainFile := session.MainFile(t.config.OutputFormat)
+	tmp := mainFile + ".rebuild"
+	os.Remove(tmp)
+
+	writer, err := newTranscriptWriter(t.config.OutputFormat, tmp, t.config.Whisper.WordTimestamps)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		chunk := AudioChunk{Num: entry.Num, Start: entry.Start, End: entry.End, WindowStart: entry.Start}
+		if err := writer.Append(chunk, entry.Segments); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+
+	if err := os.Rename(tmp, mainFile); err != nil {
+		return err
+	}
+
+	// Reopen lazily so subtitle numbering continues from the rebuilt file
+	session.writer = nil
+	session.maxWritten = entries[len(entries)-1].Num
+	fmt.Printf("Rebuilt transcript in chunk order: %s\n", mainFile)
+	return nil
+}
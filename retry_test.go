@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRebuildTranscriptInChunkOrder(t *testing.T) {
+	tests := []struct {
+		format string
+		want   func(nums ...int) string
+	}{
+		{
+			format: "txt",
+			want: func(nums ...int) string {
+				var blocks []string
+				for _, n := range nums {
+					start := (n - 1) * 30
+					blocks = append(blocks, fmt.Sprintf("[%s - %s]\nchunk %d was picked up again here\n",
+						formatTimestamp(start), formatTimestamp(start+5), n))
+				}
+				return strings.Join(blocks, "\n\n")
+			},
+		},
+		{
+			format: "srt",
+			want: func(nums ...int) string {
+				var cues string
+				for i, n := range nums {
+					start := (n - 1) * 30
+					cues += fmt.Sprintf("%d\n00:%02d:%02d,000 --> 00:%02d:%02d,000\nchunk %d was picked up again here\n\n",
+						i+1, start/60, start%60, (start+5)/60, (start+5)%60, n)
+				}
+				return cues
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			dir := t.TempDir()
+			configDir := t.TempDir()
+			config := fmt.Sprintf(`{"temp_dir": %q, "vad": "off", "output_format": %q}`, t.TempDir(), tt.format)
+			if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tr, err := NewTranscriber(configDir)
+			if err != nil {
+				t.Fatalf("NewTranscriber: %v", err)
+			}
+			tr.whisperService = &WhisperService{config: &tr.config, backend: chunkNumBackend{}}
+
+			session := newSession("20250101_120000", filepath.Join(dir, "run_20250101_120000"))
+			if err := session.enableManifest(tt.format); err != nil {
+				t.Fatal(err)
+			}
+			commit := func(num int) {
+				t.Helper()
+				path := filepath.Join(dir, fmt.Sprintf("chunk_%s_%d.wav", session.ID, num))
+				writeSilence(t, path, 30)
+				start := float64(num-1) * 30
+				chunk := AudioChunk{Num: num, Path: path, Start: start, End: start + 30, WindowStart: start}
+				session.chunkRecorded(chunk)
+				result, err := tr.transcribeChunkAudio(session, chunk)
+				if err != nil {
+					t.Fatalf("transcribeChunkAudio %d: %v", num, err)
+				}
+				if err := tr.commitChunk(session, chunk, result, false); err != nil {
+					t.Fatalf("commitChunk %d: %v", num, err)
+				}
+			}
+
+			// Chunk 3 failed and landed late on retry
+			for _, num := range []int{1, 2, 4, 5, 3} {
+				commit(num)
+			}
+
+			data, err := os.ReadFile(session.JournalPath())
+			if err != nil {
+				t.Fatal(err)
+			}
+			var journaled []int
+			for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+				var entry journalEntry
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatal(err)
+				}
+				journaled = append(journaled, entry.Num)
+			}
+			if want := []int{1, 2, 4, 5, 3}; !reflect.DeepEqual(journaled, want) {
+				t.Fatalf("journal order = %v, want %v", journaled, want)
+			}
+
+			if got, want := readFile(t, session.MainFile(tt.format)), tt.want(1, 2, 3, 4, 5); got != want {
+				t.Errorf("rebuilt transcript:\n%s\nwant:\n%s", got, want)
+			}
+
+			// Appending carries on from the rebuilt file
+			commit(6)
+			if got, want := readFile(t, session.MainFile(tt.format)), tt.want(1, 2, 3, 4, 5, 6); got != want {
+				t.Errorf("after next chunk:\n%s\nwant:\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,498 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// sessionInfo describes a session managed by the API server
+type sessionInfo struct {
+	ID         string     `json:"id"`
+	Kind       string     `json:"kind"`   // "live" or "upload"
+	Status     string     `json:"status"` // "running", "stopping", "completed" or "failed"
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Transcript string     `json:"transcript"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// Server exposes live sessions and file transcription over HTTP, reusing the
+// transcriber's recorder and whisper service
+type Server struct {
+	transcriber *Transcriber
+	outputDir   string
+	addr        string // Listen address, used to recognize requests meant for this server
+
+	mu       sync.Mutex
+	sessions map[string]*sessionInfo
+	order    []string // Session IDs in creation order
+	live     *Session // The running live session, if any
+	wg       sync.WaitGroup
+}
+
+func NewServer(t *Transcriber, outputDir string) *Server {
+	if t.broadcaster == nil {
+		t.broadcaster = NewBroadcaster()
+	}
+	return &Server{
+		transcriber: t,
+		outputDir:   outputDir,
+		sessions:    make(map[string]*sessionInfo),
+	}
+}
+
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /sessions", s.handleListSessions)
+	mux.HandleFunc("POST /sessions", s.handleStartSession)
+	mux.HandleFunc("GET /sessions/{id}", s.handleGetSession)
+	mux.HandleFunc("POST /sessions/{id}/stop", s.handleStopSession)
+	mux.HandleFunc("GET /sessions/{id}/transcript", s.handleGetTranscript)
+	mux.HandleFunc("GET /sessions/{id}/events", s.handleEvents)
+	mux.HandleFunc("GET /sessions/{id}/ws", s.handleWebSocket)
+	mux.HandleFunc("POST /transcriptions", s.handleUpload)
+
+	// Event streams and WebSockets are as sensitive as the POST routes: any
+	// page could otherwise read the live transcript
+	return s.guard(mux.ServeHTTP)
+}
+
+// ListenAndServe serves the API on addr until interrupted, then stops any
+// live session and waits for running transcriptions to finish
+func (s *Server) ListenAndServe(addr string) error {
+	if err := os.MkdirAll(s.outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	s.addr = addr
+	srv := &http.Server{Addr: addr, Handler: s.Handler()}
+
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- srv.ListenAndServe()
+	}()
+
+	fmt.Printf("Transcriber API listening on http://%s\n", addr)
+	fmt.Println("Press Ctrl+C to stop the server.")
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-sigChan:
+		fmt.Println("\nReceived interrupt signal. Shutting down server...")
+	}
+
+	s.mu.Lock()
+	live := s.live
+	s.mu.Unlock()
+	if live != nil {
+		s.transcriber.stopSession(live)
+	}
+	s.wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// newSessionLocked registers a session with an ID unique within this server
+func (s *Server) newSessionLocked(kind string) *sessionInfo {
+	id := time.Now().Format("20060102_150405")
+	for n := 2; s.sessions[id] != nil; n++ {
+		id = fmt.Sprintf("%s_%d", time.Now().Format("20060102_150405"), n)
+	}
+
+	info := &sessionInfo{
+		ID:        id,
+		Kind:      kind,
+		Status:    "running",
+		StartedAt: time.Now(),
+	}
+	s.sessions[id] = info
+	s.order = append(s.order, id)
+	return info
+}
+
+// finish records the outcome of a session
+func (s *Server) finish(info *sessionInfo, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	info.FinishedAt = &now
+	if err != nil {
+		info.Status = "failed"
+		info.Error = err.Error()
+	} else {
+		info.Status = "completed"
+	}
+	if s.live != nil && s.live.ID == info.ID {
+		s.live = nil
+	}
+	s.transcriber.broadcaster.Finish(info.ID)
+}
+
+func (s *Server) lookup(id string) (sessionInfo, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	info, ok := s.sessions[id]
+	if !ok {
+		return sessionInfo{}, false
+	}
+	return *info, true
+}
+
+func (s *Server) handleListSessions(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	list := make([]sessionInfo, 0, len(s.order))
+	for _, id := range s.order {
+		list = append(list, *s.sessions[id])
+	}
+	s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (s *Server) handleStartSession(w http.ResponseWriter, r *http.Request) {
+	if !requireJSON(w, r) {
+		return
+	}
+	var req struct {
+		Duration string `json:"duration"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+		return
+	}
+	duration, err := parseDuration(req.Duration)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid duration %q: %v", req.Duration, err))
+		return
+	}
+
+	s.mu.Lock()
+	if live := s.live; live != nil {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, fmt.Sprintf("live session %s is already running", live.ID))
+		return
+	}
+	info := s.newSessionLocked("live")
+	session := newSession(info.ID, filepath.Join(s.outputDir, "run_"+info.ID))
+	info.Transcript = session.MainFile(s.transcriber.config.OutputFormat)
+	s.live = session
+	created := *info
+	s.mu.Unlock()
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.finish(info, s.transcriber.recordSession(session, duration, true))
+	}()
+
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) handleGetSession(w http.ResponseWriter, r *http.Request) {
+	info, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+func (s *Server) handleStopSession(w http.ResponseWriter, r *http.Request) {
+	if !requireJSON(w, r) {
+		return
+	}
+	id := r.PathValue("id")
+
+	s.mu.Lock()
+	info, ok := s.sessions[id]
+	if !ok {
+		s.mu.Unlock()
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	if info.Kind != "live" || info.Status != "running" {
+		s.mu.Unlock()
+		writeError(w, http.StatusConflict, fmt.Sprintf("session %s is not a running live session", id))
+		return
+	}
+	info.Status = "stopping"
+	stopped := *info
+	session := s.live
+	s.mu.Unlock()
+
+	if session != nil {
+		s.transcriber.stopSession(session)
+	}
+	writeJSON(w, http.StatusAccepted, stopped)
+}
+
+func (s *Server) handleGetTranscript(w http.ResponseWriter, r *http.Request) {
+	info, ok := s.lookup(r.PathValue("id"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+
+	data, err := os.ReadFile(info.Transcript)
+	if os.IsNotExist(err) {
+		writeError(w, http.StatusNotFound, "no transcript yet")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", transcriptContentType(s.transcriber.config.OutputFormat))
+	w.Write(data)
+}
+
+// handleEvents streams a session's chunk events as Server-Sent Events,
+// starting with a replay of the session so far
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, ok := s.lookup(id); !ok {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+
+	replay, events, cancel := s.transcriber.broadcaster.Subscribe(id)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range replay {
+		writeSSE(w, event)
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			writeSSE(w, event)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeSSE(w io.Writer, event ChunkEvent) {
+	data, _ := json.Marshal(event)
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+}
+
+// handleWebSocket streams a session's chunk events as WebSocket text
+// messages, starting with a replay of the session so far
+func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if _, ok := s.lookup(id); !ok {
+		writeError(w, http.StatusNotFound, "session not found")
+		return
+	}
+	if !isWebSocketUpgrade(r) {
+		writeError(w, http.StatusBadRequest, "expected a websocket upgrade")
+		return
+	}
+
+	replay, events, cancel := s.transcriber.broadcaster.Subscribe(id)
+	defer cancel()
+
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		fmt.Printf("WebSocket upgrade failed: %v\n", err)
+		return
+	}
+	defer conn.Close()
+
+	clientGone := make(chan struct{})
+	go func() {
+		defer close(clientGone)
+		conn.readLoop()
+	}()
+
+	send := func(event ChunkEvent) error {
+		data, _ := json.Marshal(event)
+		return conn.WriteText(data)
+	}
+
+	for _, event := range replay {
+		if err := send(event); err != nil {
+			return
+		}
+	}
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			if err := send(event); err != nil {
+				return
+			}
+		case <-clientGone:
+			return
+		}
+	}
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "expected an audio file in the \"file\" form field")
+		return
+	}
+	defer file.Close()
+
+	name := filepath.Base(header.Filename)
+	baseName := strings.TrimSuffix(name, filepath.Ext(name))
+
+	s.mu.Lock()
+	info := s.newSessionLocked("upload")
+	session := newSession(info.ID, filepath.Join(s.outputDir, fmt.Sprintf("upload_%s_%s", info.ID, baseName)))
+	info.Transcript = session.MainFile(s.transcriber.config.OutputFormat)
+	created := *info
+	s.mu.Unlock()
+
+	// Keep the upload in the temp directory until it has been transcribed.
+	// Only a known audio extension is taken from the client's filename;
+	// ffmpeg detects the format either way.
+	ext := strings.ToLower(filepath.Ext(name))
+	if !supportedAudioExtensions[ext] {
+		ext = ""
+	}
+	uploadPath := filepath.Join(s.transcriber.config.TempDir, fmt.Sprintf("upload_%s%s", info.ID, ext))
+	out, err := os.Create(uploadPath)
+	if err == nil {
+		_, err = io.Copy(out, file)
+		out.Close()
+	}
+	if err != nil {
+		os.Remove(uploadPath)
+		s.finish(info, fmt.Errorf("failed to save upload: %v", err))
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to save upload: %v", err))
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer os.Remove(uploadPath)
+		s.finish(info, s.transcriber.transcribeFile(session, uploadPath))
+	}()
+
+	writeJSON(w, http.StatusAccepted, created)
+}
+
+// guard rejects requests that a web page open in the user's browser could
+// make: those sent from another origin, and those addressed to a host name
+// other than the server's own, as happens with DNS rebinding
+func (s *Server) guard(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := s.checkOrigin(r); err != nil {
+			writeError(w, http.StatusForbidden, err.Error())
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) checkOrigin(r *http.Request) error {
+	if !s.allowedHost(r.Host) {
+		return fmt.Errorf("requests for host %q are not allowed", r.Host)
+	}
+
+	// Browsers send Origin with every cross-origin request; tools like curl
+	// send none
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil || !strings.EqualFold(u.Host, r.Host) {
+		return fmt.Errorf("cross-origin requests are not allowed")
+	}
+	return nil
+}
+
+// allowedHost reports whether a request's Host names this server: an IP
+// address, localhost or the listen address's own host name
+func (s *Server) allowedHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	if net.ParseIP(host) != nil || strings.EqualFold(host, "localhost") {
+		return true
+	}
+	listenHost, _, err := net.SplitHostPort(s.addr)
+	return err == nil && listenHost != "" && strings.EqualFold(host, listenHost)
+}
+
+// requireJSON rejects requests without a JSON content type. A web page can
+// only send one to another origin after a CORS preflight, which this server
+// never approves.
+func requireJSON(w http.ResponseWriter, r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return false
+	}
+	return true
+}
+
+func transcriptContentType(format string) string {
+	switch format {
+	case "json":
+		return "application/json"
+	case "srt":
+		return "application/x-subrip; charset=utf-8"
+	case "vtt":
+		return "text/vtt; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	s := NewServer(&Transcriber{config: Config{OutputFormat: "txt", TempDir: t.TempDir()}}, t.TempDir())
+	s.addr = "127.0.0.1:8765"
+	return s
+}
+
+func TestServerRejectsBrowserRequests(t *testing.T) {
+	s := newTestServer(t)
+	handler := s.Handler()
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		host        string
+		origin      string
+		contentType string
+		want        int
+	}{
+		{"start without content type", "POST", "/sessions", "127.0.0.1:8765", "", "", http.StatusUnsupportedMediaType},
+		{"start as form post", "POST", "/sessions", "127.0.0.1:8765", "", "text/plain", http.StatusUnsupportedMediaType},
+		{"start from another origin", "POST", "/sessions", "127.0.0.1:8765", "https://evil.example", "application/json", http.StatusForbidden},
+		{"start through rebound host", "POST", "/sessions", "evil.example:8765", "http://evil.example:8765", "application/json", http.StatusForbidden},
+		{"stop without content type", "POST", "/sessions/x/stop", "127.0.0.1:8765", "", "", http.StatusUnsupportedMediaType},
+		{"stop from another origin", "POST", "/sessions/x/stop", "localhost:8765", "null", "application/json", http.StatusForbidden},
+		{"stop from same origin", "POST", "/sessions/x/stop", "localhost:8765", "http://localhost:8765", "application/json", http.StatusNotFound},
+		{"websocket from another origin", "GET", "/sessions/x/ws", "127.0.0.1:8765", "https://evil.example", "", http.StatusForbidden},
+		{"events from another origin", "GET", "/sessions/x/events", "127.0.0.1:8765", "https://evil.example", "", http.StatusForbidden},
+		{"transcript through rebound host", "GET", "/sessions/x/transcript", "evil.example:8765", "", "", http.StatusForbidden},
+		{"websocket from same origin", "GET", "/sessions/x/ws", "127.0.0.1:8765", "http://127.0.0.1:8765", "", http.StatusNotFound},
+		{"upload from another origin", "POST", "/transcriptions", "127.0.0.1:8765", "https://evil.example", "multipart/form-data; boundary=x", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(""))
+			req.Host = tt.host
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("got HTTP %d, want %d: %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+// fakeCaptureFFmpeg records each run and then captures until it is told to
+// quit on stdin, as ffmpeg does
+const fakeCaptureFFmpeg = `#!/bin/sh
+echo run >> "$(dirname "$0")/runs"
+read quit
+exit 0
+`
+
+// newCaptureTranscriber returns a transcriber whose recorder runs
+// fakeCaptureFFmpeg, and the file counting its runs
+func newCaptureTranscriber(t *testing.T) (*Transcriber, string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "ffmpeg"), []byte(fakeCaptureFFmpeg), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	configDir := t.TempDir()
+	config := fmt.Sprintf(`{"temp_dir": %q}`, t.TempDir())
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := NewTranscriber(configDir)
+	if err != nil {
+		t.Fatalf("NewTranscriber: %v", err)
+	}
+	return tr, filepath.Join(bin, "runs")
+}
+
+func TestStopBeforeRecordingStarts(t *testing.T) {
+	tr, runs := newCaptureTranscriber(t)
+	session := newSession("20250101_120000", filepath.Join(t.TempDir(), "run_20250101_120000"))
+
+	// The stop arrives while the session is still being set up
+	tr.stopSession(session)
+
+	done := make(chan error, 1)
+	go func() { done <- tr.recordSession(session, 0, true) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("recordSession: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		tr.recorder.Stop()
+		t.Fatal("session kept recording after being stopped")
+	}
+	if _, err := os.Stat(runs); err == nil {
+		t.Error("capture process started for a stopped session")
+	}
+}
+
+func TestServerStartThenStop(t *testing.T) {
+	tr, _ := newCaptureTranscriber(t)
+	s := NewServer(tr, t.TempDir())
+	s.addr = "127.0.0.1:8765"
+	handler := s.Handler()
+
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", path, strings.NewReader("{}"))
+		req.Host = s.addr
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := post("/sessions")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("start: HTTP %d: %s", rec.Code, rec.Body.String())
+	}
+	var info sessionInfo
+	if err := json.Unmarshal(rec.Body.Bytes(), &info); err != nil {
+		t.Fatal(err)
+	}
+	if rec := post("/sessions/" + info.ID + "/stop"); rec.Code != http.StatusAccepted {
+		t.Fatalf("stop: HTTP %d: %s", rec.Code, rec.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, _ := s.lookup(info.ID)
+		if got.Status == "completed" {
+			break
+		}
+		if got.Status == "failed" {
+			t.Fatalf("session failed: %s", got.Error)
+		}
+		if time.Now().After(deadline) {
+			tr.recorder.Stop()
+			t.Fatalf("session still %q after stop", got.Status)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	s.wg.Wait()
+}
@@ -0,0 +1,523 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Chunk states recorded in the session manifest
+const (
+	chunkPending     = "pending"
+	chunkTranscribed = "transcribed"
+	chunkSkipped     = "skipped"
+	chunkSilent      = "silent" // Dropped by voice detection
+	chunkFailed      = "failed" // Queued for retry
+)
+
+// manifestChunk is one recorded chunk as tracked in the session manifest.
+// Failed chunks form the session's retry queue.
+type manifestChunk struct {
+	Num         int        `json:"num"`
+	Start       float64    `json:"start"`
+	End         float64    `json:"end"`
+	WindowStart float64    `json:"window_start"`
+	Path        string     `json:"path"`
+	Status      string     `json:"status"`
+	Attempts    int        `json:"attempts,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+	NextRetry   *time.Time `json:"next_retry,omitempty"` // Unset once automatic retries are exhausted
+}
+
+// journalEntry is one chunk as written to the transcript. The journal lets
+// the transcript be rebuilt in chunk order when a retried chunk lands late.
+type journalEntry struct {
+	Num      int                 `json:"num"`
+	Start    float64             `json:"start"`
+	End      float64             `json:"end"`
+	Text     string              `json:"text"`
+	Segments []TranscriptSegment `json:"segments"`
+}
+
+// sessionManifest is written next to a live session's transcript so the
+// session can be resumed after a crash or restart
+type sessionManifest struct {
+	SessionID    string          `json:"session_id"`
+	OutputPath   string          `json:"output_path"`
+	OutputFormat string          `json:"output_format"`
+	StartedAt    time.Time       `json:"started_at"`
+	LastChunk    int             `json:"last_transcribed_chunk"`
+	PendingFiles []string        `json:"pending_files"`
+	Chunks       []manifestChunk `json:"chunks"`
+}
+
+// Session is the state of one transcript being built up chunk by chunk
+type Session struct {
+	ID         string
+	OutputPath string // Transcript path without the format extension
+	StartedAt  time.Time
+
+	lastText   string           // Raw text of the previous chunk, used to stitch overlaps
+	lastNum    int              // Number of the chunk lastText belongs to
+	maxWritten int              // Highest chunk number written to the transcript
+	writer     TranscriptWriter // Opened on the first append
+
+	// Placeholders handed out by redaction, keyed by type and value
+	placeholders    map[string]string
+	redactionCounts map[string]int
+
+	// End of the latest transcribed chunk's text, carried into the next prompt
+	tailNum  int
+	tailText string
+
+	// Manifest tracking, enabled for live sessions
+	mu           sync.Mutex
+	stopped      bool // Set by requestStop, possibly before recording starts
+	manifest     bool
+	outputFormat string
+	chunks       []manifestChunk
+}
+
+func newSession(id, outputPath string) *Session {
+	return &Session{
+		ID:         id,
+		OutputPath: outputPath,
+		StartedAt:  time.Now(),
+	}
+}
+
+// requestStop marks the session as stopping. Recording checks it around
+// starting the recorder, so a stop that arrives first is not lost.
+func (s *Session) requestStop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = true
+}
+
+// stopRequested reports whether requestStop has been called
+func (s *Session) stopRequested() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+// MainFile returns the transcript file for the given output format
+func (s *Session) MainFile(format string) string {
+	return s.OutputPath + "." + format
+}
+
+// ManifestPath returns the session manifest file next to the transcript
+func (s *Session) ManifestPath() string {
+	return s.OutputPath + ".session.json"
+}
+
+// JournalPath returns the file recording every chunk written to the transcript
+func (s *Session) JournalPath() string {
+	return s.OutputPath + ".chunks.jsonl"
+}
+
+// transcriptWriter returns the session's writer for format, opening it on
+// first use
+func (s *Session) transcriptWriter(format string, words bool) (TranscriptWriter, error) {
+	if s.writer == nil {
+		writer, err := newTranscriptWriter(format, s.MainFile(format), words)
+		if err != nil {
+			return nil, err
+		}
+		s.writer = writer
+	}
+	return s.writer, nil
+}
+
+// enableManifest starts tracking chunks in the session manifest
+func (s *Session) enableManifest(outputFormat string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.manifest = true
+	s.outputFormat = outputFormat
+	return s.saveManifestLocked()
+}
+
+// chunkRecorded registers a newly recorded chunk as pending
+func (s *Session) chunkRecorded(chunk AudioChunk) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.manifest {
+		return
+	}
+
+	entry := manifestChunk{
+		Num:         chunk.Num,
+		Start:       chunk.Start,
+		End:         chunk.End,
+		WindowStart: chunk.WindowStart,
+		Path:        chunk.Path,
+		Status:      chunkPending,
+	}
+	for i := range s.chunks {
+		if s.chunks[i].Num == chunk.Num {
+			s.chunks[i] = entry
+			s.saveManifestOrWarn()
+			return
+		}
+	}
+	s.chunks = append(s.chunks, entry)
+	sort.Slice(s.chunks, func(i, j int) bool { return s.chunks[i].Num < s.chunks[j].Num })
+	s.saveManifestOrWarn()
+}
+
+// chunkFinished records the final status of a chunk
+func (s *Session) chunkFinished(num int, status string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.manifest {
+		return
+	}
+
+	for i := range s.chunks {
+		if s.chunks[i].Num == num {
+			s.chunks[i].Status = status
+		}
+	}
+	s.saveManifestOrWarn()
+}
+
+// chunkFailed puts a chunk on the retry queue. Retries back off
+// exponentially from backoff; after maxAttempts the chunk stays queued for
+// the retry command but is no longer retried automatically.
+func (s *Session) chunkFailed(num int, err error, backoff time.Duration, maxAttempts int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.manifest {
+		return
+	}
+
+	for i := range s.chunks {
+		c := &s.chunks[i]
+		if c.Num != num {
+			continue
+		}
+		c.Status = chunkFailed
+		c.Attempts++
+		c.LastError = err.Error()
+		c.NextRetry = nil
+		if c.Attempts < maxAttempts {
+			next := time.Now().Add(backoff << (c.Attempts - 1))
+			c.NextRetry = &next
+			fmt.Printf("Chunk %d queued for retry in %v (attempt %d of %d)\n",
+				num, time.Until(next).Round(time.Second), c.Attempts+1, maxAttempts)
+		} else {
+			fmt.Printf("Chunk %d failed %d time(s); run `retry %s` to try again later\n", num, c.Attempts, s.ID)
+		}
+	}
+	s.saveManifestOrWarn()
+}
+
+// pendingChunks returns the chunks recorded but not yet transcribed, in order
+func (s *Session) pendingChunks() []AudioChunk {
+	return s.chunksWhere(func(c manifestChunk) bool { return c.Status == chunkPending })
+}
+
+// outstandingChunks returns pending and failed chunks, in order
+func (s *Session) outstandingChunks() []AudioChunk {
+	return s.chunksWhere(func(c manifestChunk) bool {
+		return c.Status == chunkPending || c.Status == chunkFailed
+	})
+}
+
+// takeDueRetries returns failed chunks whose backoff has elapsed and marks
+// them pending again, so they are handed out only once per attempt
+func (s *Session) takeDueRetries(now time.Time) []AudioChunk {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []AudioChunk
+	for i := range s.chunks {
+		c := &s.chunks[i]
+		if c.Status != chunkFailed || c.NextRetry == nil || now.Before(*c.NextRetry) {
+			continue
+		}
+		c.Status = chunkPending
+		c.NextRetry = nil
+		due = append(due, c.audioChunk())
+	}
+	if len(due) > 0 {
+		s.saveManifestOrWarn()
+	}
+	return due
+}
+
+func (s *Session) chunksWhere(match func(manifestChunk) bool) []AudioChunk {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var chunks []AudioChunk
+	for _, c := range s.chunks {
+		if match(c) {
+			chunks = append(chunks, c.audioChunk())
+		}
+	}
+	return chunks
+}
+
+func (c manifestChunk) audioChunk() AudioChunk {
+	return AudioChunk{
+		Num:         c.Num,
+		Path:        c.Path,
+		Start:       c.Start,
+		End:         c.End,
+		WindowStart: c.WindowStart,
+	}
+}
+
+// journalChunk records a chunk written to the transcript. It is a no-op for
+// sessions without a manifest.
+func (s *Session) journalChunk(chunk AudioChunk, text string, segments []TranscriptSegment) error {
+	s.mu.Lock()
+	enabled := s.manifest
+	s.mu.Unlock()
+	if !enabled {
+		return nil
+	}
+
+	data, err := json.Marshal(journalEntry{
+		Num:      chunk.Num,
+		Start:    chunk.Start,
+		End:      chunk.End,
+		Text:     text,
+		Segments: segments,
+	})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.JournalPath(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// readJournal returns the journaled chunks in chunk order, keeping the latest
+// entry for each chunk
+func (s *Session) readJournal() ([]journalEntry, error) {
+	data, err := os.ReadFile(s.JournalPath())
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	byNum := make(map[int]journalEntry)
+	for _, line := range strings.Split(string(data), "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var entry journalEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			// A crash can leave a partial last line
+			continue
+		}
+		byNum[entry.Num] = entry
+	}
+
+	entries := make([]journalEntry, 0, len(byNum))
+	for _, entry := range byNum {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Num < entries[j].Num })
+	return entries, nil
+}
+
+// previousText returns the text of the chunk before num, for stitching
+func (s *Session) previousText(num int) string {
+	if s.lastNum == num-1 {
+		return s.lastText
+	}
+	entries, err := s.readJournal()
+	if err != nil {
+		return ""
+	}
+	for _, entry := range entries {
+		if entry.Num == num-1 {
+			return entry.Text
+		}
+	}
+	return ""
+}
+
+// nextChunkNum returns the number for the next recorded chunk
+func (s *Session) nextChunkNum() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.chunks) == 0 {
+		return 1
+	}
+	return s.chunks[len(s.chunks)-1].Num + 1
+}
+
+// resumeOffset returns where newly recorded audio starts on the session
+// timeline: after the last recorded chunk, or at the wall-clock time since
+// the session started if the process was down for a while
+func (s *Session) resumeOffset() float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.chunks) == 0 {
+		return 0
+	}
+	offset := s.chunks[len(s.chunks)-1].End
+	if elapsed := time.Since(s.StartedAt).Seconds(); elapsed > offset {
+		offset = elapsed
+	}
+	return offset
+}
+
+func (s *Session) saveManifestOrWarn() {
+	if err := s.saveManifestLocked(); err != nil {
+		fmt.Printf("Warning: failed to save session manifest: %v\n", err)
+	}
+}
+
+func (s *Session) saveManifestLocked() error {
+	m := sessionManifest{
+		SessionID:    s.ID,
+		OutputPath:   s.OutputPath,
+		OutputFormat: s.outputFormat,
+		StartedAt:    s.StartedAt,
+		PendingFiles: []string{},
+		Chunks:       s.chunks,
+	}
+	for _, c := range s.chunks {
+		switch c.Status {
+		case chunkTranscribed, chunkSkipped:
+			if c.Num > m.LastChunk {
+				m.LastChunk = c.Num
+			}
+		case chunkPending, chunkFailed:
+			m.PendingFiles = append(m.PendingFiles, c.Path)
+		}
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write atomically so a crash never leaves a truncated manifest
+	tmp := s.ManifestPath() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.ManifestPath())
+}
+
+// findSessionManifest resolves a --resume argument, which may be a manifest
+// path, a transcript path or a session ID in outputDir
+func findSessionManifest(ref, outputDir string) (string, error) {
+	candidates := []string{
+		ref,
+		ref + ".session.json",
+		strings.TrimSuffix(ref, filepath.Ext(ref)) + ".session.json",
+		filepath.Join(outputDir, ref+".session.json"),
+		filepath.Join(outputDir, "run_"+ref+".session.json"),
+	}
+	for _, path := range candidates {
+		if !strings.HasSuffix(path, ".session.json") {
+			continue
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no session manifest found for %q in %s", ref, outputDir)
+}
+
+// loadSession restores a live session from its manifest. Chunk audio for the
+// session left in tempDir but missing from the manifest, such as the segment
+// being recorded when the process died, is added as pending.
+func loadSession(manifestPath, tempDir string) (*Session, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session manifest: %v", err)
+	}
+
+	var m sessionManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid session manifest %s: %v", manifestPath, err)
+	}
+
+	s := &Session{
+		ID:           m.SessionID,
+		OutputPath:   m.OutputPath,
+		StartedAt:    m.StartedAt,
+		manifest:     true,
+		outputFormat: m.OutputFormat,
+		chunks:       m.Chunks,
+	}
+	s.recoverOrphanChunks(tempDir)
+
+	entries, err := s.readJournal()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session journal: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Num > s.maxWritten {
+			s.maxWritten = entry.Num
+		}
+	}
+	return s, nil
+}
+
+func (s *Session) recoverOrphanChunks(tempDir string) {
+	known := make(map[string]bool)
+	for _, c := range s.chunks {
+		known[c.Path] = true
+	}
+
+	// Match names rather than globbing, as temp_dir may contain glob
+	// metacharacters
+	prefix := fmt.Sprintf("chunk_%s_", s.ID)
+	entries, _ := os.ReadDir(tempDir)
+	for _, entry := range entries {
+		name := entry.Name()
+		file := filepath.Join(tempDir, name)
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) || !strings.HasSuffix(name, ".wav") || known[file] {
+			continue
+		}
+		num, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".wav"))
+		if err != nil {
+			continue
+		}
+		audio, err := readWAV(file)
+		if err != nil || len(audio.Samples) == 0 {
+			continue
+		}
+
+		// Place the orphan right after the chunk recorded before it
+		start := 0.0
+		for _, c := range s.chunks {
+			if c.Num < num && c.End > start {
+				start = c.End
+			}
+		}
+		fmt.Printf("Recovered unlisted chunk audio: %s\n", file)
+		s.chunks = append(s.chunks, manifestChunk{
+			Num:         num,
+			Start:       start,
+			End:         start + audio.Duration(),
+			WindowStart: start,
+			Path:        file,
+			Status:      chunkPending,
+		})
+	}
+	sort.Slice(s.chunks, func(i, j int) bool { return s.chunks[i].Num < s.chunks[j].Num })
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeSilence writes secs of silent 16 kHz mono audio to path
+func writeSilence(t *testing.T, path string, secs float64) {
+	t.Helper()
+	if err := writeWAV(path, &wavAudio{SampleRate: 16000, Samples: make([]int16, int(secs*16000))}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// interruptedSession leaves a session in outputDir as a crash would: chunk 1
+// transcribed, chunk 2 recorded but not transcribed, and chunk 3 written to
+// tempDir but never added to the manifest. It returns the manifest path.
+func interruptedSession(t *testing.T, outputDir, tempDir string) string {
+	t.Helper()
+	session := newSession("20250101_120000", filepath.Join(outputDir, "run_20250101_120000"))
+	chunkPath := func(num int) string {
+		return filepath.Join(tempDir, fmt.Sprintf("chunk_%s_%d.wav", session.ID, num))
+	}
+
+	if err := session.enableManifest("txt"); err != nil {
+		t.Fatal(err)
+	}
+	first := AudioChunk{Num: 1, Path: chunkPath(1), Start: 0, End: 30}
+	session.chunkRecorded(first)
+	if err := session.journalChunk(first, "first chunk", []TranscriptSegment{{Start: 0, End: 30, Text: "first chunk"}}); err != nil {
+		t.Fatal(err)
+	}
+	session.chunkFinished(1, chunkTranscribed)
+
+	writeSilence(t, chunkPath(2), 31.5)
+	session.chunkRecorded(AudioChunk{Num: 2, Path: chunkPath(2), Start: 30, End: 61.5, WindowStart: 30})
+
+	writeSilence(t, chunkPath(3), 5)
+
+	// Not chunks of this session
+	writeSilence(t, filepath.Join(tempDir, "chunk_20250102_090000_5.wav"), 5)
+	writeSilence(t, filepath.Join(tempDir, fmt.Sprintf("chunk_%s_final.wav", session.ID)), 5)
+	writeSilence(t, chunkPath(4), 0)
+
+	return session.ManifestPath()
+}
+
+func TestLoadSessionRecoversChunks(t *testing.T) {
+	// Glob metacharacters in temp_dir must not hide the orphan
+	tempDir := filepath.Join(t.TempDir(), "temp [1]")
+	if err := os.MkdirAll(tempDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	manifestPath := interruptedSession(t, t.TempDir(), tempDir)
+
+	session, err := loadSession(manifestPath, tempDir)
+	if err != nil {
+		t.Fatalf("loadSession: %v", err)
+	}
+
+	want := []AudioChunk{
+		{Num: 2, Path: filepath.Join(tempDir, "chunk_20250101_120000_2.wav"), Start: 30, End: 61.5, WindowStart: 30},
+		{Num: 3, Path: filepath.Join(tempDir, "chunk_20250101_120000_3.wav"), Start: 61.5, End: 66.5, WindowStart: 61.5},
+	}
+	if got := session.pendingChunks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("pending chunks = %+v, want %+v", got, want)
+	}
+	if got := session.nextChunkNum(); got != 4 {
+		t.Errorf("next chunk = %d, want 4", got)
+	}
+	if session.maxWritten != 1 {
+		t.Errorf("maxWritten = %d, want 1", session.maxWritten)
+	}
+
+	// New audio continues after the last chunk, or after the time the
+	// process was down if that is later
+	session.StartedAt = time.Now()
+	if got := session.resumeOffset(); got != 66.5 {
+		t.Errorf("resume offset = %v, want 66.5", got)
+	}
+	session.StartedAt = time.Now().Add(-2 * time.Minute)
+	if got := session.resumeOffset(); got < 120 || got > 130 {
+		t.Errorf("resume offset after downtime = %v, want about 120", got)
+	}
+}
+
+// chunkNumBackend answers each chunk with text naming its number
+type chunkNumBackend struct{}
+
+func (chunkNumBackend) Transcribe(audioFile, outputPath, prompt string) (*TranscriptionResult, error) {
+	num := strings.TrimSuffix(filepath.Base(audioFile), ".wav")
+	num = num[strings.LastIndex(num, "_")+1:]
+	text := fmt.Sprintf("chunk %s was picked up again here", num)
+	return &TranscriptionResult{Segments: []TranscriptSegment{{Start: 0, End: 5, Text: text}}}, nil
+}
+
+func TestResumeTranscribesPendingChunks(t *testing.T) {
+	outputDir, tempDir := t.TempDir(), t.TempDir()
+	manifestPath := interruptedSession(t, outputDir, tempDir)
+
+	configDir := t.TempDir()
+	config := fmt.Sprintf(`{"temp_dir": %q, "vad": "off"}`, tempDir)
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := NewTranscriber(configDir)
+	if err != nil {
+		t.Fatalf("NewTranscriber: %v", err)
+	}
+	tr.whisperService = &WhisperService{config: &tr.config, backend: chunkNumBackend{}}
+
+	session, err := loadSession(manifestPath, tempDir)
+	if err != nil {
+		t.Fatalf("loadSession: %v", err)
+	}
+	session.requestStop() // Only the leftovers, no new recording
+	if err := tr.recordSession(session, 0, false); err != nil {
+		t.Fatalf("recordSession: %v", err)
+	}
+
+	data, err := os.ReadFile(session.MainFile("txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[0:30 - 0:35]\nchunk 2 was picked up again here\n\n\n" +
+		"[1:01 - 1:07]\nchunk 3 was picked up again here\n"
+	if string(data) != want {
+		t.Errorf("transcript = %q, want %q", data, want)
+	}
+
+	resumed, err := loadSession(manifestPath, tempDir)
+	if err != nil {
+		t.Fatalf("loadSession: %v", err)
+	}
+	if pending := resumed.outstandingChunks(); len(pending) != 0 {
+		t.Errorf("chunks still outstanding: %+v", pending)
+	}
+	if resumed.maxWritten != 3 {
+		t.Errorf("maxWritten = %d, want 3", resumed.maxWritten)
+	}
+}
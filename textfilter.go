@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TextFilterConfig declares one step of the text_filters chain
+type TextFilterConfig struct {
+	Type    string            `json:"type"`              // "regex", "dictionary", "case" or "whitespace"
+	Pattern string            `json:"pattern,omitempty"` // regex: expression to match
+	Replace string            `json:"replace,omitempty"` // regex: replacement, may refer to groups as $1
+	Words   map[string]string `json:"words,omitempty"`   // dictionary: word or phrase to its replacement
+	Mode    string            `json:"mode,omitempty"`    // case: "sentence", "lower" or "upper"
+}
+
+// textFilter rewrites one line of transcript text
+type textFilter func(string) string
+
+// textPipeline runs text filters in the order they were declared
+type textPipeline []textFilter
+
+// newTextPipeline compiles the text_filters config
+func newTextPipeline(configs []TextFilterConfig) (textPipeline, error) {
+	var pipeline textPipeline
+	for i, config := range configs {
+		filter, err := newTextFilter(config)
+		if err != nil {
+			return nil, fmt.Errorf("text_filters[%d]: %v", i, err)
+		}
+		pipeline = append(pipeline, filter)
+	}
+	return pipeline, nil
+}
+
+func newTextFilter(config TextFilterConfig) (textFilter, error) {
+	switch strings.ToLower(config.Type) {
+	case "regex":
+		re, err := regexp.Compile(config.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %v", err)
+		}
+		return func(text string) string {
+			return re.ReplaceAllString(text, config.Replace)
+		}, nil
+
+	case "dictionary":
+		return newDictionaryFilter(config.Words)
+
+	case "case":
+		switch strings.ToLower(config.Mode) {
+		case "sentence":
+			return sentenceCase, nil
+		case "lower":
+			return strings.ToLower, nil
+		case "upper":
+			return strings.ToUpper, nil
+		default:
+			return nil, fmt.Errorf("unsupported case mode %q (use sentence, lower or upper)", config.Mode)
+		}
+
+	case "whitespace":
+		return cleanWhitespace, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported filter type %q (use regex, dictionary, case or whitespace)", config.Type)
+	}
+}
+
+// newDictionaryFilter replaces whole words and phrases, ignoring case.
+// Longer entries are tried first so "open ai api" wins over "open ai".
+func newDictionaryFilter(words map[string]string) (textFilter, error) {
+	if len(words) == 0 {
+		return nil, fmt.Errorf("dictionary has no words")
+	}
+
+	keys := make([]string, 0, len(words))
+	for key := range words {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if len(keys[i]) != len(keys[j]) {
+			return len(keys[i]) > len(keys[j])
+		}
+		return keys[i] < keys[j]
+	})
+
+	alternatives := make([]string, len(keys))
+	replacements := make(map[string]string, len(keys))
+	for i, key := range keys {
+		alternatives[i] = regexp.QuoteMeta(key)
+		replacements[strings.ToLower(key)] = words[key]
+	}
+	re, err := regexp.Compile(`(?i)\b(?:` + strings.Join(alternatives, "|") + `)\b`)
+	if err != nil {
+		return nil, fmt.Errorf("invalid dictionary: %v", err)
+	}
+
+	return func(text string) string {
+		return re.ReplaceAllStringFunc(text, func(match string) string {
+			if replacement, ok := replacements[strings.ToLower(match)]; ok {
+				return replacement
+			}
+			// (?i) also matches case variants that lowercase differently,
+			// such as the long s "ſ" for "s"
+			for _, key := range keys {
+				if strings.EqualFold(key, match) {
+					return words[key]
+				}
+			}
+			return match
+		})
+	}, nil
+}
+
+// sentenceCase capitalizes the first letter of every sentence and leaves
+// the rest alone, so names keep their casing
+func sentenceCase(text string) string {
+	var b strings.Builder
+	start := true
+	for _, r := range text {
+		if start && unicode.IsLetter(r) {
+			r = unicode.ToUpper(r)
+			start = false
+		} else if strings.ContainsRune(".!?", r) {
+			start = true
+		} else if !unicode.IsSpace(r) && !strings.ContainsRune(`"'(`, r) {
+			start = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var spaceBeforePunctuation = regexp.MustCompile(`\s+([,.;:!?])`)
+
+// cleanWhitespace collapses runs of whitespace and removes spaces before
+// punctuation
+func cleanWhitespace(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	return spaceBeforePunctuation.ReplaceAllString(text, "$1")
+}
+
+func (p textPipeline) apply(text string) string {
+	for _, filter := range p {
+		text = filter(text)
+	}
+	return text
+}
+
+// applySegments filters the text of every segment, dropping segments left
+// empty
+func (p textPipeline) applySegments(segments []TranscriptSegment) []TranscriptSegment {
+	if len(p) == 0 {
+		return segments
+	}
+
+	kept := segments[:0]
+	for _, seg := range segments {
+		if text := p.apply(seg.Text); text != seg.Text {
+			seg.Text = text
+			seg.Tokens = nil // No longer match the text
+		}
+		if strings.TrimSpace(seg.Text) != "" {
+			kept = append(kept, seg)
+		}
+	}
+	return kept
+}
+
+// Matches the "[start - end]" header of a chunk in txt transcripts
+var textChunkHeader = regexp.MustCompile(`^\[[\d:]+ - [\d:]+\]$`)
+
+// postprocessTranscript runs the pipeline over an existing transcript in
+// place, keeping the original as <path>.bak. The format is taken from the
+// file extension. It returns the number of lines or segments changed.
+func postprocessTranscript(path string, p textPipeline) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	var output []byte
+	changed := 0
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		var transcript TranscriptionResult
+		if err := json.Unmarshal(data, &transcript); err != nil {
+			return 0, fmt.Errorf("%s is not a JSON transcript: %v", path, err)
+		}
+		for _, seg := range transcript.Segments {
+			if p.apply(seg.Text) != seg.Text {
+				changed++
+			}
+		}
+		transcript.Segments = p.applySegments(transcript.Segments)
+		if output, err = json.MarshalIndent(transcript, "", "  "); err != nil {
+			return 0, err
+		}
+
+	case ".srt", ".vtt":
+		output, changed = filterCues(data, p)
+
+	default:
+		output, changed, err = filterLines(data, p, func(line string) bool {
+			trimmed := strings.TrimSpace(line)
+			return trimmed == "" || textChunkHeader.MatchString(trimmed)
+		})
+	}
+	if err != nil {
+		return 0, err
+	}
+	if changed == 0 {
+		return 0, nil
+	}
+
+	if err := os.WriteFile(path+".bak", data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to back up transcript: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, output, 0644); err != nil {
+		return 0, err
+	}
+	return changed, os.Rename(tmp, path)
+}
+
+// filterLines runs the pipeline over every line that keep does not exempt
+func filterLines(data []byte, p textPipeline, keep func(string) bool) ([]byte, int, error) {
+	var b strings.Builder
+	changed := 0
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !keep(line) {
+			if filtered := p.apply(line); filtered != line {
+				line = filtered
+				changed++
+			}
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	output := b.String()
+	if !strings.HasSuffix(string(data), "\n") {
+		output = strings.TrimSuffix(output, "\n")
+	}
+	return []byte(output), changed, nil
+}
+
+// filterCues runs the pipeline over the payload of every subtitle cue.
+// Numbers, timings and blocks without timings, such as the WEBVTT header,
+// are kept. Cues left without text are dropped and the rest renumbered.
+func filterCues(data []byte, p textPipeline) ([]byte, int) {
+	var blocks [][]string
+	var block []string
+	for _, line := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(line) == "" {
+			if len(block) > 0 {
+				blocks = append(blocks, block)
+				block = nil
+			}
+			continue
+		}
+		block = append(block, line)
+	}
+	if len(block) > 0 {
+		blocks = append(blocks, block)
+	}
+
+	var b strings.Builder
+	changed := 0
+	cue := 1
+	for _, block := range blocks {
+		timing := -1
+		for i, line := range block {
+			if strings.Contains(line, " --> ") {
+				timing = i
+				break
+			}
+		}
+		if timing < 0 {
+			b.WriteString(strings.Join(block, "\n") + "\n\n")
+			continue
+		}
+
+		var payload []string
+		for _, line := range block[timing+1:] {
+			filtered := p.apply(line)
+			if filtered != line {
+				changed++
+			}
+			if strings.TrimSpace(filtered) != "" {
+				payload = append(payload, filtered)
+			}
+		}
+		if len(payload) == 0 {
+			continue
+		}
+
+		header := block[:timing+1]
+		if timing == 1 && isCueNumber(strings.TrimSpace(header[0])) {
+			header[0] = fmt.Sprint(cue)
+		}
+		cue++
+		b.WriteString(strings.Join(append(header, payload...), "\n") + "\n\n")
+	}
+	return []byte(b.String()), changed
+}
+
+func isCueNumber(line string) bool {
+	for len(line) > 0 {
+		r, size := utf8.DecodeRuneInString(line)
+		if !unicode.IsDigit(r) {
+			return false
+		}
+		line = line[size:]
+	}
+	return true
+}
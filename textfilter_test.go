@@ -0,0 +1,192 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTextFilters(t *testing.T) {
+	tests := []struct {
+		name    string
+		filters []TextFilterConfig
+		input   string
+		want    string
+	}{
+		{
+			name:    "regex with groups",
+			filters: []TextFilterConfig{{Type: "regex", Pattern: `(\d+) percent`, Replace: "$1%"}},
+			input:   "up 20 percent on 5 percent",
+			want:    "up 20% on 5%",
+		},
+		{
+			name:    "dictionary ignores case and matches whole words",
+			filters: []TextFilterConfig{{Type: "dictionary", Words: map[string]string{"k8s": "Kubernetes"}}},
+			input:   "K8S and k8s but not k8smith",
+			want:    "Kubernetes and Kubernetes but not k8smith",
+		},
+		{
+			name:    "dictionary prefers the longer phrase",
+			filters: []TextFilterConfig{{Type: "dictionary", Words: map[string]string{"open ai": "OpenAI", "open ai api": "OpenAI API"}}},
+			input:   "open ai built the open ai api",
+			want:    "OpenAI built the OpenAI API",
+		},
+		{
+			name:    "dictionary case variants that lowercase differently",
+			filters: []TextFilterConfig{{Type: "dictionary", Words: map[string]string{"ask": "ASK"}}},
+			input:   "a\u017fk them",
+			want:    "ASK them",
+		},
+		{
+			name:    "sentence case",
+			filters: []TextFilterConfig{{Type: "case", Mode: "sentence"}},
+			input:   `hello there. "is Bob in?" yes`,
+			want:    `Hello there. "Is Bob in?" Yes`,
+		},
+		{
+			name:    "lower case",
+			filters: []TextFilterConfig{{Type: "case", Mode: "lower"}},
+			input:   "Hello THERE",
+			want:    "hello there",
+		},
+		{
+			name:    "upper case",
+			filters: []TextFilterConfig{{Type: "case", Mode: "UPPER"}},
+			input:   "Hello there",
+			want:    "HELLO THERE",
+		},
+		{
+			name:    "whitespace",
+			filters: []TextFilterConfig{{Type: "whitespace"}},
+			input:   "  so ,  well\tthen  . ",
+			want:    "so, well then.",
+		},
+		{
+			name: "filters run in order",
+			filters: []TextFilterConfig{
+				{Type: "dictionary", Words: map[string]string{"gonna": "going to"}},
+				{Type: "regex", Pattern: `going to`, Replace: "will"},
+			},
+			input: "gonna call",
+			want:  "will call",
+		},
+		{
+			name: "a later filter sees an earlier one's output only",
+			filters: []TextFilterConfig{
+				{Type: "regex", Pattern: `going to`, Replace: "will"},
+				{Type: "dictionary", Words: map[string]string{"gonna": "going to"}},
+			},
+			input: "gonna call",
+			want:  "going to call",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := newTextPipeline(tt.filters)
+			if err != nil {
+				t.Fatalf("newTextPipeline: %v", err)
+			}
+			if got := p.apply(tt.input); got != tt.want {
+				t.Errorf("apply(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextFilterConfigErrors(t *testing.T) {
+	tests := []struct {
+		filter TextFilterConfig
+		want   string
+	}{
+		{filter: TextFilterConfig{Type: "regex", Pattern: "("}, want: "invalid pattern"},
+		{filter: TextFilterConfig{Type: "dictionary"}, want: "no words"},
+		{filter: TextFilterConfig{Type: "case", Mode: "title"}, want: "unsupported case mode"},
+		{filter: TextFilterConfig{Type: "spelling"}, want: "unsupported filter type"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter.Type, func(t *testing.T) {
+			_, err := newTextPipeline([]TextFilterConfig{{Type: "whitespace"}, tt.filter})
+			if err == nil || !strings.Contains(err.Error(), "text_filters[1]") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want text_filters[1]: %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostprocessTranscript(t *testing.T) {
+	p, err := newTextPipeline([]TextFilterConfig{
+		{Type: "regex", Pattern: `(?i)\b(um|uh)\b,?`},
+		{Type: "whitespace"},
+		{Type: "case", Mode: "sentence"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ext     string
+		input   string
+		want    string
+		changed int
+	}{
+		{
+			ext:     ".txt",
+			input:   "[0:00 - 0:30]\num, hello there\nThem: all good\n\n\n[0:30 - 1:00]\nuh\n",
+			want:    "[0:00 - 0:30]\nHello there\nThem: all good\n\n\n[0:30 - 1:00]\n\n",
+			changed: 2,
+		},
+		{
+			ext: ".srt",
+			input: "1\n00:00:00,000 --> 00:00:02,000\num, hello\n\n" +
+				"2\n00:00:02,000 --> 00:00:03,000\nuh\n\n" +
+				"3\n00:00:03,000 --> 00:00:05,000\nsee you\nUm\n\n",
+			want: "1\n00:00:00,000 --> 00:00:02,000\nHello\n\n" +
+				"2\n00:00:03,000 --> 00:00:05,000\nSee you\n\n",
+			changed: 4,
+		},
+		{
+			ext: ".vtt",
+			input: "WEBVTT\n\n" +
+				"1\n00:00:00.000 --> 00:00:02.000\nuh\n\n" +
+				"2\n00:00:02.000 --> 00:00:03.000\nright, um, yes\n\n",
+			want: "WEBVTT\n\n" +
+				"1\n00:00:02.000 --> 00:00:03.000\nRight, yes\n\n",
+			changed: 2,
+		},
+		{
+			ext: ".json",
+			input: `{"segments": [{"start": 0, "end": 2, "text": "um"},
+				{"start": 2, "end": 4, "text": "fine thanks", "tokens": [{"text": "fine", "p": 0.9}]}]}`,
+			want:    "{\n  \"segments\": [\n    {\n      \"start\": 2,\n      \"end\": 4,\n      \"text\": \"Fine thanks\"\n    }\n  ]\n}",
+			changed: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ext, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "run_20250101_120000"+tt.ext)
+			if err := os.WriteFile(path, []byte(tt.input), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			changed, err := postprocessTranscript(path, p)
+			if err != nil {
+				t.Fatalf("postprocessTranscript: %v", err)
+			}
+			if changed != tt.changed {
+				t.Errorf("changed = %d, want %d", changed, tt.changed)
+			}
+			if got := readFile(t, path); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			if backup := readFile(t, path+".bak"); backup != tt.input {
+				t.Errorf("backup = %q, want the original", backup)
+			}
+
+			// A second run has nothing left to change
+			if changed, err := postprocessTranscript(path, p); err != nil || changed != 0 {
+				t.Errorf("second run changed %d, err %v", changed, err)
+			}
+		})
+	}
+}
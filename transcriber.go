@@ -12,14 +12,51 @@ import (
 )
 
 type Config struct {
-	ModelPath                  string `json:"model_path"`
-	Language                   string `json:"language"`
-	TempDir                    string `json:"temp_dir"`
-	OutputFormat               string `json:"output_format"`
-	WhisperCmd                 string `json:"whisper_cmd"`
-	RecordingCmd               string `json:"recording_cmd"`
-	ChunkDurationInSecs        int    `json:"chunk_duration_in_secs"`         // Duration in seconds for each chunk
-	MinRequiredUniqueWordCount int    `json:"min_required_unique_word_count"` // Minimum unique words to process a chunk
+	ModelPath                  string                   `json:"model_path"`
+	Language                   string                   `json:"language"`
+	TempDir                    string                   `json:"temp_dir"`
+	OutputFormat               string                   `json:"output_format"`
+	WhisperCmd                 string                   `json:"whisper_cmd"`
+	Backend                    string                   `json:"backend"`      // "cli" runs whisper_cmd, "server" posts to a whisper.cpp server, "openai" to an OpenAI-compatible API
+	ServerURL                  string                   `json:"server_url"`   // Base URL of the whisper.cpp server
+	APIBaseURL                 string                   `json:"api_base_url"` // Base URL of the OpenAI-compatible API, including /v1
+	APIModel                   string                   `json:"api_model"`    // Model name sent to the OpenAI-compatible API
+	APIKey                     string                   `json:"api_key"`      // Optional bearer token for the OpenAI-compatible API
+	RecordingCmd               string                   `json:"recording_cmd"`
+	InputDevice                string                   `json:"input_device"`                   // Capture device, optionally prefixed with its backend (see `devices`)
+	CaptureSource              string                   `json:"capture_source"`                 // "mic", "system" (monitor_device), "mixed" (both) or "dual" (both, labeled by channel)
+	MonitorDevice              string                   `json:"monitor_device"`                 // Device that captures the system's audio output
+	ChannelNames               []string                 `json:"channel_names"`                  // Speaker labels for the input and monitor channels of dual recordings
+	StreamFormat               string                   `json:"stream_format"`                  // Raw PCM format of run --input streams, e.g. "s16le"; empty detects the container
+	StreamSampleRate           int                      `json:"stream_sample_rate"`             // Sample rate of raw PCM streams
+	StreamChannels             int                      `json:"stream_channels"`                // Channel count of raw PCM streams
+	VAD                        string                   `json:"vad"`                            // "energy" drops silent chunks before transcription, "off" disables it
+	VADThresholdDB             float64                  `json:"vad_threshold_db"`               // Frame level in dBFS that counts as speech
+	VADMinSpeechMs             int                      `json:"vad_min_speech_ms"`              // Speech a chunk needs to be transcribed
+	HallucinationFilter        string                   `json:"hallucination_filter"`           // "on" drops likely hallucinated and repeated text from whisper output, "off" disables it
+	HallucinationPhrases       []string                 `json:"hallucination_phrases"`          // Segments consisting only of one of these phrases are dropped
+	RepeatThreshold            int                      `json:"repeat_threshold"`               // Repeats in a row at which words or segments are collapsed into one
+	MinAvgTokenProb            *float64                 `json:"min_avg_token_prob"`             // Segments whose tokens average a lower probability are dropped; 0 turns the check off
+	FilterDebug                bool                     `json:"filter_debug"`                   // Log removed text and record it next to the transcript
+	TextFilters                []TextFilterConfig       `json:"text_filters"`                   // Rewrites applied in order to every chunk's text before it is written
+	InitialPrompt              string                   `json:"initial_prompt"`                 // Text passed to whisper as a prompt to bias style and spelling
+	Vocabulary                 []string                 `json:"vocabulary"`                     // Terms whisper should recognize, passed in the prompt
+	Profiles                   map[string]PromptProfile `json:"profiles"`                       // Named vocabularies and initial prompts
+	Profile                    string                   `json:"profile"`                        // Profile whose vocabulary and initial prompt are used
+	PromptCarryWords           int                      `json:"prompt_carry_words"`             // Words of the previous chunk's text added to the prompt; 0 disables
+	Whisper                    WhisperOptions           `json:"whisper"`                        // Decoding parameters passed to the backend
+	RedactPII                  bool                     `json:"redact_pii"`                     // Replace emails, phone, card and national ID numbers with placeholders before anything is written
+	RedactTypes                []string                 `json:"redact_types"`                   // Kinds of PII to redact: email, phone, card, national_id
+	RedactLocales              []string                 `json:"redact_locales"`                 // Countries whose phone and national ID formats are detected: us, gb, in
+	RedactionKeyFile           string                   `json:"redaction_key_file"`             // AES key for the encrypted placeholder map; empty keeps no map
+	TranscriptionWorkers       int                      `json:"transcription_workers"`          // Chunks transcribed at the same time
+	RetryMaxAttempts           int                      `json:"retry_max_attempts"`             // Transcription attempts per chunk during a session
+	RetryBackoffSecs           int                      `json:"retry_backoff_secs"`             // Delay before the first retry, doubled on each attempt
+	ChunkDurationInSecs        int                      `json:"chunk_duration_in_secs"`         // Duration in seconds for each chunk
+	ChunkMinSecs               int                      `json:"chunk_min_secs"`                 // Shortest chunk when ending chunks at pauses
+	ChunkMaxSecs               int                      `json:"chunk_max_secs"`                 // Longest chunk when ending chunks at pauses
+	ChunkOverlapSecs           int                      `json:"chunk_overlap_secs"`             // Audio shared between consecutive chunks
+	MinRequiredUniqueWordCount int                      `json:"min_required_unique_word_count"` // Minimum unique words to process a chunk
 }
 
 type Transcriber struct {
@@ -28,6 +65,9 @@ type Transcriber struct {
 	stopChan       chan struct{}
 	recorder       *Recorder
 	whisperService *WhisperService
+	textFilters    textPipeline
+	redactor       *redactor    // Nil unless redact_pii is on
+	broadcaster    *Broadcaster // Set when chunk events have listeners, e.g. in serve mode
 }
 
 func NewTranscriber(configPath string) (*Transcriber, error) {
@@ -55,7 +95,7 @@ func NewTranscriber(configPath string) (*Transcriber, error) {
 	}
 
 	// Initialize recorder with the configured audio input
-	t.recorder = NewRecorderWithDefaultDevice(false)
+	t.recorder = t.newRecorder()
 
 	// Initialize whisper service
 	t.whisperService = NewWhisperService(&t.config)
@@ -65,6 +105,7 @@ func NewTranscriber(configPath string) (*Transcriber, error) {
 
 func (t *Transcriber) loadConfig() error {
 	workDir := filepath.Dir(t.configPath)
+	minAvgTokenProb := 0.2
 	// Set sensible defaults
 	t.config = Config{
 		ModelPath:                  filepath.Join(workDir, "ggml-large-v3-turbo-q5_0.bin"),
@@ -72,7 +113,31 @@ func (t *Transcriber) loadConfig() error {
 		TempDir:                    "/tmp/transcriber",
 		OutputFormat:               "txt",
 		WhisperCmd:                 "whisper-cli",
+		Backend:                    "cli",
+		ServerURL:                  "http://127.0.0.1:8080",
+		APIBaseURL:                 "https://api.openai.com/v1",
+		APIModel:                   "whisper-1",
 		RecordingCmd:               "ffmpeg",
+		CaptureSource:              "mic",
+		MonitorDevice:              defaultMonitorDevice,
+		ChannelNames:               []string{"Me", "Them"},
+		StreamSampleRate:           16000,
+		StreamChannels:             1,
+		VAD:                        "energy",
+		VADThresholdDB:             -45,
+		VADMinSpeechMs:             300,
+		HallucinationFilter:        "on",
+		HallucinationPhrases:       defaultHallucinationPhrases,
+		RepeatThreshold:            3,
+		MinAvgTokenProb:            &minAvgTokenProb,
+		TextFilters:                []TextFilterConfig{},
+		Vocabulary:                 []string{},
+		Profiles:                   map[string]PromptProfile{},
+		RedactTypes:                []string{"email", "phone", "card", "national_id"},
+		RedactLocales:              []string{"us"},
+		TranscriptionWorkers:       1,
+		RetryMaxAttempts:           3,
+		RetryBackoffSecs:           15,
 		ChunkDurationInSecs:        30, // Default 30 seconds per chunk
 		MinRequiredUniqueWordCount: 5,  // Minimum unique words to process a chunk
 	}
@@ -101,17 +166,169 @@ func (t *Transcriber) loadConfig() error {
 		t.config.TempDir = loadedConfig.TempDir
 	}
 	if loadedConfig.OutputFormat != "" {
-		t.config.OutputFormat = loadedConfig.OutputFormat
+		t.config.OutputFormat = strings.ToLower(loadedConfig.OutputFormat)
+	}
+	if !supportedOutputFormats[t.config.OutputFormat] {
+		return fmt.Errorf("unsupported output_format %q (use txt, json, srt or vtt; other whisper formats are no longer passed through)", t.config.OutputFormat)
 	}
 	if loadedConfig.WhisperCmd != "" {
 		t.config.WhisperCmd = loadedConfig.WhisperCmd
 	}
+	if loadedConfig.Backend != "" {
+		t.config.Backend = strings.ToLower(loadedConfig.Backend)
+	}
+	if !supportedBackends[t.config.Backend] {
+		return fmt.Errorf("unsupported backend %q (use cli, server or openai)", t.config.Backend)
+	}
+	if loadedConfig.ServerURL != "" {
+		t.config.ServerURL = loadedConfig.ServerURL
+	}
+	if loadedConfig.APIBaseURL != "" {
+		t.config.APIBaseURL = loadedConfig.APIBaseURL
+	}
+	if loadedConfig.APIModel != "" {
+		t.config.APIModel = loadedConfig.APIModel
+	}
+	if loadedConfig.APIKey != "" {
+		t.config.APIKey = loadedConfig.APIKey
+	}
+	if loadedConfig.InputDevice != "" {
+		t.config.InputDevice = loadedConfig.InputDevice
+	}
+	if loadedConfig.CaptureSource != "" {
+		t.config.CaptureSource = strings.ToLower(loadedConfig.CaptureSource)
+	}
+	if !supportedCaptureSources[t.config.CaptureSource] {
+		return fmt.Errorf("unsupported capture_source %q (use mic, system, mixed or dual)", t.config.CaptureSource)
+	}
+	if loadedConfig.MonitorDevice != "" {
+		t.config.MonitorDevice = loadedConfig.MonitorDevice
+	}
+	if len(loadedConfig.ChannelNames) > 0 {
+		t.config.ChannelNames = loadedConfig.ChannelNames
+	}
+	if loadedConfig.StreamFormat != "" {
+		t.config.StreamFormat = loadedConfig.StreamFormat
+	}
+	if loadedConfig.StreamSampleRate > 0 {
+		t.config.StreamSampleRate = loadedConfig.StreamSampleRate
+	}
+	if loadedConfig.StreamChannels > 0 {
+		t.config.StreamChannels = loadedConfig.StreamChannels
+	}
 	if loadedConfig.RecordingCmd != "" {
 		t.config.RecordingCmd = loadedConfig.RecordingCmd
 	}
+	if loadedConfig.VAD != "" {
+		t.config.VAD = strings.ToLower(loadedConfig.VAD)
+	}
+	if !supportedVADModes[t.config.VAD] {
+		return fmt.Errorf("unsupported vad %q (use energy or off)", t.config.VAD)
+	}
+	if loadedConfig.VADThresholdDB != 0 {
+		t.config.VADThresholdDB = loadedConfig.VADThresholdDB
+	}
+	if t.config.VADThresholdDB >= 0 {
+		return fmt.Errorf("vad_threshold_db (%v) must be negative", t.config.VADThresholdDB)
+	}
+	if loadedConfig.VADMinSpeechMs > 0 {
+		t.config.VADMinSpeechMs = loadedConfig.VADMinSpeechMs
+	}
+	if loadedConfig.HallucinationFilter != "" {
+		t.config.HallucinationFilter = strings.ToLower(loadedConfig.HallucinationFilter)
+	}
+	if t.config.HallucinationFilter != "on" && t.config.HallucinationFilter != "off" {
+		return fmt.Errorf("unsupported hallucination_filter %q (use on or off)", t.config.HallucinationFilter)
+	}
+	if loadedConfig.HallucinationPhrases != nil {
+		t.config.HallucinationPhrases = loadedConfig.HallucinationPhrases
+	}
+	if loadedConfig.RepeatThreshold > 0 {
+		t.config.RepeatThreshold = loadedConfig.RepeatThreshold
+	}
+	// Unset keeps the default, while an explicit 0 turns the check off
+	if loadedConfig.MinAvgTokenProb != nil {
+		t.config.MinAvgTokenProb = loadedConfig.MinAvgTokenProb
+	}
+	if p := *t.config.MinAvgTokenProb; p < 0 || p > 1 {
+		return fmt.Errorf("min_avg_token_prob (%v) must be between 0 and 1", p)
+	}
+	t.config.FilterDebug = loadedConfig.FilterDebug
+	if loadedConfig.TextFilters != nil {
+		t.config.TextFilters = loadedConfig.TextFilters
+	}
+	if t.textFilters, err = newTextPipeline(t.config.TextFilters); err != nil {
+		return err
+	}
+	t.config.RedactPII = loadedConfig.RedactPII
+	if len(loadedConfig.RedactTypes) > 0 {
+		t.config.RedactTypes = loadedConfig.RedactTypes
+	}
+	if len(loadedConfig.RedactLocales) > 0 {
+		t.config.RedactLocales = loadedConfig.RedactLocales
+	}
+	if loadedConfig.RedactionKeyFile != "" {
+		t.config.RedactionKeyFile = loadedConfig.RedactionKeyFile
+	}
+	if loadedConfig.InitialPrompt != "" {
+		t.config.InitialPrompt = loadedConfig.InitialPrompt
+	}
+	if loadedConfig.Vocabulary != nil {
+		t.config.Vocabulary = loadedConfig.Vocabulary
+	}
+	if loadedConfig.Profiles != nil {
+		t.config.Profiles = loadedConfig.Profiles
+	}
+	if loadedConfig.Profile != "" {
+		t.config.Profile = loadedConfig.Profile
+	}
+	if err := t.validateProfile(); err != nil {
+		return err
+	}
+	if loadedConfig.PromptCarryWords > 0 {
+		t.config.PromptCarryWords = loadedConfig.PromptCarryWords
+	}
+	if t.redactor, err = newRedactor(&t.config); err != nil {
+		return err
+	}
+	t.config.Whisper = loadedConfig.Whisper
+	if err := t.config.Whisper.validate(t.config.Backend); err != nil {
+		return err
+	}
+	if loadedConfig.TranscriptionWorkers > 0 {
+		t.config.TranscriptionWorkers = loadedConfig.TranscriptionWorkers
+	}
+	if loadedConfig.RetryMaxAttempts > 0 {
+		t.config.RetryMaxAttempts = loadedConfig.RetryMaxAttempts
+	}
+	if loadedConfig.RetryBackoffSecs > 0 {
+		t.config.RetryBackoffSecs = loadedConfig.RetryBackoffSecs
+	}
 	if loadedConfig.ChunkDurationInSecs > 0 {
 		t.config.ChunkDurationInSecs = loadedConfig.ChunkDurationInSecs
 	}
+	if loadedConfig.ChunkMinSecs > 0 {
+		t.config.ChunkMinSecs = loadedConfig.ChunkMinSecs
+	}
+	if loadedConfig.ChunkMaxSecs > 0 {
+		t.config.ChunkMaxSecs = loadedConfig.ChunkMaxSecs
+	}
+	if t.config.ChunkMinSecs > t.config.ChunkDurationInSecs ||
+		(t.config.ChunkMaxSecs > 0 && t.config.ChunkMaxSecs < t.config.ChunkDurationInSecs) {
+		return fmt.Errorf("chunk_min_secs (%d) and chunk_max_secs (%d) must bracket chunk_duration_in_secs (%d)",
+			t.config.ChunkMinSecs, t.config.ChunkMaxSecs, t.config.ChunkDurationInSecs)
+	}
+	if loadedConfig.ChunkOverlapSecs > 0 {
+		t.config.ChunkOverlapSecs = loadedConfig.ChunkOverlapSecs
+	}
+	if t.config.ChunkOverlapSecs >= t.config.ChunkDurationInSecs {
+		return fmt.Errorf("chunk_overlap_secs (%d) must be less than chunk_duration_in_secs (%d)",
+			t.config.ChunkOverlapSecs, t.config.ChunkDurationInSecs)
+	}
+	if t.config.ChunkMinSecs > 0 && t.config.ChunkOverlapSecs >= t.config.ChunkMinSecs {
+		return fmt.Errorf("chunk_overlap_secs (%d) must be less than chunk_min_secs (%d)",
+			t.config.ChunkOverlapSecs, t.config.ChunkMinSecs)
+	}
 
 	return t.ensureTempDir()
 }
@@ -128,75 +345,164 @@ func (t *Transcriber) ensureTempDir() error {
 	return os.MkdirAll(t.config.TempDir, 0755)
 }
 
-// Remove the recordAudio method and replace with simpler recording
-func (t *Transcriber) recordAudio(outputFile string, duration int) error {
-	return t.recorder.Record(outputFile, duration)
-}
+// transcribeChunkAudio runs the backend on a chunk. It is safe to call from
+// several goroutines at once.
+func (t *Transcriber) transcribeChunkAudio(session *Session, chunk AudioChunk) (*TranscriptionResult, error) {
+	// Base name for whisper's intermediate output for this chunk. It holds the
+	// unfiltered, unredacted text, so it stays in the temp directory with the
+	// chunk audio rather than next to the transcript.
+	tempOutputPath := filepath.Join(t.config.TempDir, strings.TrimSuffix(filepath.Base(chunk.Path), filepath.Ext(chunk.Path)))
+
+	prompt := t.chunkPrompt(session, chunk)
+
+	var result *TranscriptionResult
+	// Dual recordings carry one speaker per channel
+	if audio, err := readWAV(chunk.Path); err == nil && audio.channelCount() > 1 {
+		if result, err = t.transcribeChannels(chunk, audio, tempOutputPath, prompt); err != nil {
+			return nil, err
+		}
+	} else {
+		if result, err = t.whisperService.Transcribe(chunk.Path, tempOutputPath, prompt); err != nil {
+			return nil, fmt.Errorf("transcription failed for chunk %d: %v", chunk.Num, err)
+		}
+	}
 
-func (t *Transcriber) transcribeAudioChunk(audioFile, outputPath string, chunkNum int, removeAudioFileOnSuccess bool) error {
-	// Create temporary output file for this chunk
-	tempOutputPath := outputPath + fmt.Sprintf("_chunk_%d", chunkNum)
+	var removed []filteredText
+	if t.config.Whisper.WordTimestamps {
+		// Filter the chunk's words as whole lines, keeping each word's timing
+		result.Segments = mapLines(result.Segments, func(line TranscriptSegment) (TranscriptSegment, bool) {
+			kept, dropped := t.filterHallucinations([]TranscriptSegment{line})
+			removed = append(removed, dropped...)
+			if len(kept) == 0 {
+				return line, false
+			}
+			kept[0].Text = t.textFilters.apply(kept[0].Text)
+			return kept[0], strings.TrimSpace(kept[0].Text) != ""
+		})
+	} else {
+		result.Segments, removed = t.filterHallucinations(result.Segments)
+		result.Segments = t.textFilters.applySegments(result.Segments)
+	}
+	t.logFiltered(session, chunk, removed)
 
-	err := t.whisperService.Transcribe(audioFile, tempOutputPath)
-	if err != nil {
-		return fmt.Errorf("transcription failed for chunk %d: %v", chunkNum, err)
+	if t.config.PromptCarryWords > 0 {
+		session.rememberTail(chunk.Num, result.Text(), t.config.PromptCarryWords)
 	}
+	return result, nil
+}
 
+// commitChunk appends a chunk's transcription to the session. Chunks must be
+// committed one at a time, in order.
+func (t *Transcriber) commitChunk(session *Session, chunk AudioChunk, result *TranscriptionResult, removeAudioFileOnSuccess bool) error {
 	// Append chunk transcription to main output file
-	chunkFile := tempOutputPath + "." + t.config.OutputFormat
-	mainFile := outputPath + "." + t.config.OutputFormat
-
-	if err := t.appendTranscription(chunkFile, mainFile, chunkNum); err != nil {
-		return fmt.Errorf("failed to append chunk %d: %v", chunkNum, err)
+	if err := t.appendTranscription(session, result, chunk); err != nil {
+		return fmt.Errorf("failed to append chunk %d: %v", chunk.Num, err)
 	}
 
-	// Clean up chunk files
-	os.Remove(chunkFile)
+	session.chunkFinished(chunk.Num, chunkTranscribed)
+
+	// Clean up chunk audio
 	if removeAudioFileOnSuccess {
-		os.Remove(audioFile)
+		os.Remove(chunk.Path)
 	}
 
 	return nil
 }
 
-func (t *Transcriber) appendTranscription(chunkFile, mainFile string, chunkNum int) error {
-	// Read chunk transcription
-	chunkData, err := os.ReadFile(chunkFile)
-	if err != nil {
-		return err
+// skipSilentChunk records a chunk dropped by voice detection
+func (t *Transcriber) skipSilentChunk(session *Session, chunk AudioChunk, removeAudioFileOnSuccess bool) {
+	fmt.Printf("Skipping silent chunk %d [%s - %s]\n", chunk.Num,
+		formatTimestamp(int(chunk.Start)), formatTimestamp(int(chunk.End)))
+
+	// Nothing was written, so there is nothing for the next chunk to de-duplicate
+	session.lastText, session.lastNum = "", chunk.Num
+	session.chunkFinished(chunk.Num, chunkSilent)
+
+	if removeAudioFileOnSuccess {
+		os.Remove(chunk.Path)
+	}
+}
+
+func (t *Transcriber) appendTranscription(session *Session, result *TranscriptionResult, chunk AudioChunk) error {
+	// Shift segment offsets from the chunk's audio onto the session timeline
+	segments := make([]TranscriptSegment, 0, len(result.Segments))
+	for _, seg := range result.Segments {
+		if strings.TrimSpace(seg.Text) == "" {
+			continue
+		}
+		// Backends that only return text give no timing; span the whole chunk
+		if seg.End <= seg.Start {
+			seg.End = chunk.End - chunk.WindowStart
+		}
+		seg.Start += chunk.WindowStart
+		seg.End += chunk.WindowStart
+		segments = append(segments, seg)
+	}
+
+	// Drop text the previous chunk already covered in the shared overlap audio
+	text := result.Text()
+	prevText := session.previousText(chunk.Num)
+	session.lastText, session.lastNum = text, chunk.Num
+	if chunk.WindowStart < chunk.Start {
+		segments = dropLeadingWords(segments, overlapWordCount(prevText, text, t.config.ChunkOverlapSecs))
+		text = (&TranscriptionResult{Segments: segments}).Text()
 	}
 
 	// If number of unique words in chunk is < 5, skip appending
 	// Check for unique words
-	if t.shouldSkipChunk(chunkData, chunkNum) {
+	if len(segments) == 0 || t.shouldSkipChunk([]byte(text), chunk.Num) {
+		// Nothing was written, so there is nothing for the next chunk to de-duplicate
+		session.lastText = ""
 		return nil
 	}
 
-	// Open main file for appending
-	f, err := os.OpenFile(mainFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return err
+	// Redact before the text reaches the journal, the transcript or listeners
+	if t.redactor != nil {
+		segments = t.redactor.redactSegments(session, chunk, segments)
+		text = (&TranscriptionResult{Segments: segments}).Text()
 	}
-	defer f.Close()
 
-	// Calculate timestamp for this chunk
-	startSeconds := (chunkNum - 1) * t.config.ChunkDurationInSecs
-	endSeconds := chunkNum * t.config.ChunkDurationInSecs
-
-	// Format timestamp as MM:SS or HH:MM:SS
-	startTime := t.formatTimestamp(startSeconds)
-	endTime := t.formatTimestamp(endSeconds)
+	if err := session.journalChunk(chunk, text, segments); err != nil {
+		fmt.Printf("Warning: failed to journal chunk %d: %v\n", chunk.Num, err)
+	}
 
-	// Add chunk separator and content
-	if chunkNum > 1 {
-		f.WriteString("\n\n")
+	if chunk.Num < session.maxWritten {
+		// A retried chunk landed after later ones; rewrite the transcript in order
+		if err := t.rebuildTranscript(session); err != nil {
+			return err
+		}
+	} else {
+		writer, err := session.transcriptWriter(t.config.OutputFormat, t.config.Whisper.WordTimestamps)
+		if err != nil {
+			return err
+		}
+		if err := writer.Append(chunk, segments); err != nil {
+			return err
+		}
+		session.maxWritten = chunk.Num
 	}
-	f.WriteString(fmt.Sprintf("[%s - %s]\n", startTime, endTime))
-	f.Write(chunkData)
 
+	t.broadcaster.Publish(ChunkEvent{
+		SessionID: session.ID,
+		Chunk:     chunk.Num,
+		Start:     segments[0].Start,
+		End:       segments[len(segments)-1].End,
+		Text:      t.displayText(segments),
+		Segments:  segments,
+	})
 	return nil
 }
 
+// displayText returns segments as text for people to read: one line per
+// segment, or with word timestamps, the words run together into lines
+func (t *Transcriber) displayText(segments []TranscriptSegment) string {
+	result := &TranscriptionResult{Segments: segments}
+	if t.config.Whisper.WordTimestamps {
+		return result.LabeledWords()
+	}
+	return result.LabeledText()
+}
+
 func (t *Transcriber) shouldSkipChunk(chunkData []byte, chunkNum int) bool {
 	words := strings.Fields(string(chunkData))
 	uniqueWords := make(map[string]bool)
@@ -211,107 +517,365 @@ func (t *Transcriber) shouldSkipChunk(chunkData []byte, chunkNum int) bool {
 	return false
 }
 
-func (t *Transcriber) formatTimestamp(seconds int) string {
-	hours := seconds / 3600
-	minutes := (seconds % 3600) / 60
-	secs := seconds % 60
+// runTranscribe records and transcribes chunks until interrupted or, when
+// duration is positive, until that much audio has been recorded.
+func (t *Transcriber) runTranscribe(outputDir string, duration time.Duration, removeAudioFileOnSuccess bool) error {
+	sessionID := time.Now().Format("20060102_150405")
+	session := newSession(sessionID, filepath.Join(outputDir, "run_"+sessionID))
 
-	if hours > 0 {
-		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, secs)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %v", err)
 	}
-	return fmt.Sprintf("%d:%02d", minutes, secs)
+
+	return t.runLiveSession(session, duration, removeAudioFileOnSuccess)
 }
 
-func (t *Transcriber) runTranscribe(outputDir string, removeAudioFileOnSuccess bool) error {
-	sessionID := time.Now().Format("20060102_150405")
-	outputPath := filepath.Join(outputDir, "run_"+sessionID)
+// resumeTranscribe continues a live session from its manifest. Leftover chunk
+// audio is transcribed first, and new chunks continue the session's chunk
+// numbering and timeline.
+func (t *Transcriber) resumeTranscribe(sessionRef, outputDir string, duration time.Duration, removeAudioFileOnSuccess bool) error {
+	manifestPath, err := findSessionManifest(sessionRef, outputDir)
+	if err != nil {
+		return err
+	}
 
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %v", err)
+	session, err := loadSession(manifestPath, t.config.TempDir)
+	if err != nil {
+		return err
+	}
+	if session.outputFormat != "" && session.outputFormat != t.config.OutputFormat {
+		return fmt.Errorf("session %s was recorded as %s but output_format is %s",
+			session.ID, session.outputFormat, t.config.OutputFormat)
 	}
 
-	fmt.Printf("\n📝 Run this for Live transcription every %v secs: `tail -f %s.%s`\n\n",
-		t.config.ChunkDurationInSecs, outputPath, t.config.OutputFormat)
+	fmt.Printf("Resuming session %s from chunk %d (%d pending chunk(s))\n",
+		session.ID, session.nextChunkNum(), len(session.pendingChunks()))
+
+	return t.runLiveSession(session, duration, removeAudioFileOnSuccess)
+}
+
+// runLiveSession records session from the terminal, stopping on interrupt
+func (t *Transcriber) runLiveSession(session *Session, duration time.Duration, removeAudioFileOnSuccess bool) error {
+	fmt.Printf("\n📝 Run this for Live transcription every %v secs: `tail -f %s`\n\n",
+		t.config.ChunkDurationInSecs, session.MainFile(t.config.OutputFormat))
+	fmt.Printf("Session manifest: %s\n", session.ManifestPath())
 
 	// Set up signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigChan)
 
+	// Stop the recorder on interrupt; it flushes the current chunk and the
+	// session then finishes the pending transcriptions
+	sessionDone := make(chan struct{})
+	defer close(sessionDone)
+	go func() {
+		select {
+		case <-sigChan:
+			fmt.Println("\nReceived interrupt signal. Stopping transcription...")
+			t.stopSession(session)
+		case <-sessionDone:
+		}
+	}()
+
+	fmt.Println("Press Ctrl+C to stop recording.")
+	return t.recordSession(session, duration, removeAudioFileOnSuccess)
+}
+
+// stopSession stops recording session, whether or not its recorder has
+// started yet
+func (t *Transcriber) stopSession(session *Session) {
+	session.requestStop()
+	t.recorder.Stop()
+}
+
+// recordSession records session until the recorder is stopped or duration
+// elapses, transcribing each chunk as it completes. A single capture process
+// records the whole session so there are no gaps between chunks.
+func (t *Transcriber) recordSession(session *Session, duration time.Duration, removeAudioFileOnSuccess bool) error {
 	fmt.Printf("Starting chunked transcription. Chunk size: %d seconds\n",
 		t.config.ChunkDurationInSecs)
-	fmt.Println("Press Ctrl+C to stop recording.")
+	if duration > 0 {
+		fmt.Printf("Session will stop after %v.\n", duration)
+	}
+
+	if err := session.enableManifest(t.config.OutputFormat); err != nil {
+		return fmt.Errorf("failed to write session manifest: %v", err)
+	}
+
+	// Chunks left over from an earlier run of this session are transcribed
+	// before any new ones
+	leftovers := session.pendingChunks()
+	startNum := session.nextChunkNum()
+	offset := session.resumeOffset()
+
+	pattern := filepath.Join(escapePattern(t.config.TempDir), fmt.Sprintf("chunk_%s_%%d.wav", escapePattern(session.ID)))
+
+	// A stop requested before the recorder is running would otherwise be
+	// missed: check before starting, and again once it has started
+	recording := !session.stopRequested()
+	var chunks <-chan AudioChunk
+	if recording {
+		started, err := t.recorder.Start(pattern, t.config.ChunkDurationInSecs, startNum, duration)
+		if err != nil {
+			return fmt.Errorf("recording error: %v", err)
+		}
+		if session.stopRequested() {
+			t.recorder.Stop()
+		}
+		chunks = started
+	} else {
+		fmt.Println("Session stopped before recording started")
+		stopped := make(chan AudioChunk)
+		close(stopped)
+		chunks = stopped
+	}
 
 	// Channel to communicate audio files for transcription
-	audioFileChan := make(chan string, 2) // Buffer for 2 files
+	audioFileChan := make(chan AudioChunk, 2) // Buffer for 2 files
 	transcriptionDone := make(chan struct{})
 
-	// Start transcription goroutine
+	// Start transcription goroutine; it hands chunks to the worker pool
 	go func() {
 		defer close(transcriptionDone)
-		chunkNum := 1
-		for audioFile := range audioFileChan {
-			// Check if we have a valid recording
-			if info, err := os.Stat(audioFile); err != nil || info.Size() == 0 {
-				fmt.Printf("Warning: No valid recording for chunk %d, skipping\n", chunkNum)
-				chunkNum++
-				continue
-			}
+		pool := t.newTranscriptionPool(session, removeAudioFileOnSuccess, func(chunk AudioChunk, err error) {
+			fmt.Printf("Error processing chunk %d: %v\n", chunk.Num, err)
+			t.queueRetry(session, chunk, err)
+		})
+		defer pool.Close()
 
-			// Transcribe this chunk and append to main file
-			if err := t.transcribeAudioChunk(audioFile, outputPath, chunkNum, removeAudioFileOnSuccess); err != nil {
-				fmt.Printf("Error processing chunk %d: %v\n", chunkNum, err)
-				continue
+		submit := func(chunk AudioChunk) {
+			// Check if we have a valid recording
+			if info, err := os.Stat(chunk.Path); err != nil || info.Size() == 0 {
+				fmt.Printf("Warning: No valid recording for chunk %d, skipping\n", chunk.Num)
+				session.chunkFinished(chunk.Num, chunkSkipped)
+				return
 			}
-			chunkNum++
+			pool.Submit(chunk)
 		}
-	}()
 
-	chunkNum := 1
+		for _, chunk := range leftovers {
+			fmt.Printf("Transcribing leftover chunk %d\n", chunk.Num)
+			submit(chunk)
+		}
 
-	for {
-		// Check for interrupt signal before starting new chunk
-		select {
-		case <-sigChan:
-			fmt.Println("\nReceived interrupt signal. Stopping transcription...")
-			close(audioFileChan) // Stop sending new files for transcription
-			<-transcriptionDone  // Wait for transcription to finish
-			if chunkNum > 1 {
-				fmt.Printf("Transcription saved to: %s.%s\n", outputPath, t.config.OutputFormat)
+		// Retry failed chunks as their backoff elapses, between new chunks
+		retryTicker := time.NewTicker(time.Second)
+		defer retryTicker.Stop()
+		for {
+			select {
+			case chunk, ok := <-audioFileChan:
+				if !ok {
+					t.retryDueChunks(session, pool)
+					return
+				}
+				submit(chunk)
+			case <-retryTicker.C:
+				t.retryDueChunks(session, pool)
 			}
-			return nil
-		default:
-			// Continue with recording
 		}
+	}()
 
-		chunkDuration := t.config.ChunkDurationInSecs
-
-		audioFile := filepath.Join(t.config.TempDir, fmt.Sprintf("chunk_%s_%d.mp3", sessionID, chunkNum))
+	boundaries := newChunkBoundaries(&t.config)
+	overlapper := newChunkOverlapper(t.config.ChunkOverlapSecs)
+	chunkCount := 0
+	record := func(chunk AudioChunk) {
+		if err := boundaries.apply(&chunk); err != nil {
+			fmt.Printf("Warning: chunk %d kept its recorded boundaries: %v\n", chunk.Num, err)
+		}
 
-		fmt.Printf("Recording chunk %d (every %d seconds)...\n", chunkNum, chunkDuration)
+		fmt.Printf("Recorded chunk %d [%s - %s]\n", chunk.Num,
+			formatTimestamp(int(chunk.Start)), formatTimestamp(int(chunk.End)))
+		chunkCount++
 
-		// Record this chunk
-		if err := t.recordAudio(audioFile, chunkDuration); err != nil {
-			return fmt.Errorf("recording error for chunk %d: %v", chunkNum, err)
+		if err := overlapper.apply(&chunk); err != nil {
+			fmt.Printf("Warning: chunk %d recorded without overlap: %v\n", chunk.Num, err)
 		}
+		session.chunkRecorded(chunk)
 
 		// Send audio file for transcription (non-blocking)
 		select {
-		case audioFileChan <- audioFile:
+		case audioFileChan <- chunk:
 			// File sent successfully
 		default:
-			// Channel full, wait a bit and try again
+			// Channel full; recording continues in the background meanwhile
 			fmt.Printf("Transcription queue full, waiting...\n")
-			audioFileChan <- audioFile
+			audioFileChan <- chunk
 		}
+	}
 
-		chunkNum++
+	recorded := 0.0
+	for chunk := range chunks {
+		recorded = chunk.End
+
+		// Place the chunk on the session timeline
+		chunk.Start += offset
+		chunk.End += offset
+		record(chunk)
 	}
 
+	// Audio carried past the last pause becomes the final chunk
+	if chunk, ok, err := boundaries.flush(pattern); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	} else if ok {
+		record(chunk)
+	}
+	var recordErr error
+	if recording {
+		recordErr = t.recorder.Wait()
+	}
+
+	if recordErr == nil && recording && !t.recorder.StopRequested() {
+		// Stream inputs can run out before the session duration
+		if duration > 0 && recorded >= duration.Seconds()-1 {
+			fmt.Printf("\nReached session duration of %v. Stopping transcription...\n", duration)
+		} else {
+			fmt.Println("\nInput ended. Stopping transcription...")
+		}
+	}
+
+	close(audioFileChan) // Stop sending new files for transcription
+	<-transcriptionDone  // Wait for transcription to finish
+	if chunkCount > 0 || len(leftovers) > 0 {
+		fmt.Printf("Transcription saved to: %s\n", session.MainFile(t.config.OutputFormat))
+	}
+
+	if recordErr != nil {
+		return fmt.Errorf("recording error: %v", recordErr)
+	}
+	return nil
 }
 
 // Export methods for use in cmd.go
-func (t *Transcriber) RunTranscribe(outputDir string, removeAudioFileOnSuccess bool) error {
-	return t.runTranscribe(outputDir, removeAudioFileOnSuccess)
+func (t *Transcriber) RunTranscribe(outputDir string, duration time.Duration, removeAudioFileOnSuccess bool) error {
+	return t.runTranscribe(outputDir, duration, removeAudioFileOnSuccess)
+}
+
+func (t *Transcriber) ResumeTranscribe(sessionRef, outputDir string, duration time.Duration, removeAudioFileOnSuccess bool) error {
+	return t.resumeTranscribe(sessionRef, outputDir, duration, removeAudioFileOnSuccess)
+}
+
+func (t *Transcriber) RetrySession(sessionRef, outputDir string) error {
+	return t.retrySession(sessionRef, outputDir)
+}
+
+func (t *Transcriber) ProcessFiles(inputPath, outputDir string) error {
+	return t.processFiles(inputPath, outputDir)
+}
+
+func (t *Transcriber) Serve(addr, outputDir string) error {
+	return NewServer(t, outputDir).ListenAndServe(addr)
+}
+
+// newRecorder returns a recorder for the configured device and capture source
+func (t *Transcriber) newRecorder() *Recorder {
+	var recorder *Recorder
+	if t.config.InputDevice == "" {
+		recorder = NewRecorderWithDefaultDevice(false)
+	} else {
+		recorder = NewRecorder(t.config.InputDevice, false)
+	}
+	recorder.SetCaptureSource(t.config.CaptureSource, t.config.MonitorDevice)
+	return recorder
+}
+
+// SetInputDevice switches the recorder to device
+func (t *Transcriber) SetInputDevice(device string) {
+	t.config.InputDevice = device
+	t.recorder = t.newRecorder()
+}
+
+// SetInputStream makes the recorder read audio from input instead of a
+// capture device. input is "-" for stdin, the path of a file or FIFO, or a
+// network stream URL.
+func (t *Transcriber) SetInputStream(input string) error {
+	if input != "-" && !isNetworkURL(input) {
+		if _, err := os.Stat(input); err != nil {
+			return fmt.Errorf("input not accessible: %v", err)
+		}
+	}
+	t.recorder = NewStreamRecorder(input, StreamFormat{
+		Format:     t.config.StreamFormat,
+		SampleRate: t.config.StreamSampleRate,
+		Channels:   t.config.StreamChannels,
+	}, false)
+	return nil
+}
+
+// SetCaptureSource switches the recorder to source: mic, system, mixed or dual
+func (t *Transcriber) SetCaptureSource(source string) error {
+	source = strings.ToLower(source)
+	if !supportedCaptureSources[source] {
+		return fmt.Errorf("unsupported capture source %q (use mic, system, mixed or dual)", source)
+	}
+	t.config.CaptureSource = source
+	t.recorder = t.newRecorder()
+	return nil
+}
+
+// SetFilterDebug turns recording of text removed by the hallucination
+// filter on or off
+func (t *Transcriber) SetFilterDebug(enabled bool) {
+	t.config.FilterDebug = enabled
+}
+
+// PostprocessFile runs the text_filters chain over an existing transcript
+func (t *Transcriber) PostprocessFile(path string) error {
+	if len(t.textFilters) == 0 {
+		return fmt.Errorf("no text_filters configured in %s", t.configPath)
+	}
+	changed, err := postprocessTranscript(path, t.textFilters)
+	if err != nil {
+		return err
+	}
+	if changed == 0 {
+		fmt.Printf("No changes to %s\n", path)
+		return nil
+	}
+	fmt.Printf("Updated %d line(s) in %s (original kept as %s.bak)\n", changed, path, path)
+	return nil
+}
+
+// RevealRedactions prints the values replaced by placeholders in a
+// transcript, decrypted with redaction_key_file
+func (t *Transcriber) RevealRedactions(transcriptPath string) error {
+	if t.config.RedactionKeyFile == "" {
+		return fmt.Errorf("no redaction_key_file configured in %s", t.configPath)
+	}
+	key, err := loadRedactionKey(t.config.RedactionKeyFile, false)
+	if err != nil {
+		return err
+	}
+
+	path := transcriptPath
+	if !strings.HasSuffix(path, ".redactions.enc") {
+		path = strings.TrimSuffix(path, filepath.Ext(path)) + ".redactions.enc"
+	}
+	entries, err := (&redactor{keyPath: t.config.RedactionKeyFile, key: key}).readRedactions(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		fmt.Println("No redactions recorded.")
+		return nil
+	}
+	for _, entry := range entries {
+		fmt.Printf("%-20s chunk %-5d %s\n", entry.Placeholder, entry.Chunk, entry.Value)
+	}
+	return nil
+}
+
+// SetProfile switches to the named vocabulary and initial prompt profile
+func (t *Transcriber) SetProfile(name string) error {
+	if _, ok := t.config.Profiles[name]; !ok {
+		return fmt.Errorf("profile %q is not defined in profiles", name)
+	}
+	t.config.Profile = name
+	return nil
+}
+
+func (t *Transcriber) ListDevices() error {
+	return printAudioDevices(t.recorder.Device())
 }
 
 func (t *Transcriber) GetConfig() Config {
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Energy-based voice activity detection on the recorded PCM, so silent
+// chunks never reach the transcription backend
+
+var supportedVADModes = map[string]bool{
+	"energy": true,
+	"off":    true,
+}
+
+const vadFrameMs = 30
+
+// speechSeconds returns how much of the audio after the first skip seconds
+// is in frames louder than thresholdDB (dBFS). Channels are measured
+// together, so a frame counts as speech when any source is talking.
+func speechSeconds(audio *wavAudio, skip, thresholdDB float64) float64 {
+	channels := audio.channelCount()
+	frameLen := audio.SampleRate * vadFrameMs / 1000 * channels
+	if frameLen == 0 {
+		return 0
+	}
+
+	from := int(skip*float64(audio.SampleRate)) * channels
+	if from < 0 {
+		from = 0
+	}
+
+	speechFrames := 0
+	for i := from; i+frameLen <= len(audio.Samples); i += frameLen {
+		if frameLevelDB(audio.Samples[i:i+frameLen]) >= thresholdDB {
+			speechFrames++
+		}
+	}
+	return float64(speechFrames*vadFrameMs) / 1000
+}
+
+// frameLevelDB returns the RMS level of samples in dBFS
+func frameLevelDB(samples []int16) float64 {
+	var sum float64
+	for _, s := range samples {
+		v := float64(s) / 32768
+		sum += v * v
+	}
+	rms := math.Sqrt(sum / float64(len(samples)))
+	if rms == 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(rms)
+}
+
+// isSilentChunk reports whether the chunk's own audio, leaving out any
+// overlap taken from the previous chunk, has too little speech to transcribe
+func (t *Transcriber) isSilentChunk(chunk AudioChunk) (bool, error) {
+	if t.config.VAD == "off" {
+		return false, nil
+	}
+
+	audio, err := readWAV(chunk.Path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read chunk audio for voice detection: %v", err)
+	}
+
+	return t.isSilentAudio(audio, chunk.Start-chunk.WindowStart), nil
+}
+
+// isSilentAudio reports whether audio after the first skip seconds has too
+// little speech to transcribe
+func (t *Transcriber) isSilentAudio(audio *wavAudio, skip float64) bool {
+	if t.config.VAD == "off" {
+		return false
+	}
+	speech := speechSeconds(audio, skip, t.config.VADThresholdDB)
+	return speech*1000 < float64(t.config.VADMinSpeechMs)
+}
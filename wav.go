@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// WAV helpers for the 16-bit PCM files produced by the recorder: mono, or
+// stereo with one source per channel for dual recordings
+
+type wavAudio struct {
+	SampleRate int
+	Channels   int     // 0 is treated as mono
+	Samples    []int16 // Interleaved when there is more than one channel
+}
+
+// channelCount returns the number of channels, treating 0 as mono
+func (w *wavAudio) channelCount() int {
+	if w.Channels < 1 {
+		return 1
+	}
+	return w.Channels
+}
+
+// samplesPerSec returns the number of interleaved samples per second
+func (w *wavAudio) samplesPerSec() int {
+	return w.SampleRate * w.channelCount()
+}
+
+// Duration returns the length of the audio in seconds
+func (w *wavAudio) Duration() float64 {
+	if w.SampleRate == 0 {
+		return 0
+	}
+	return float64(len(w.Samples)) / float64(w.samplesPerSec())
+}
+
+// channel returns one channel of the audio as mono
+func (w *wavAudio) channel(c int) *wavAudio {
+	n := w.channelCount()
+	mono := &wavAudio{SampleRate: w.SampleRate, Samples: make([]int16, 0, len(w.Samples)/n)}
+	for i := c; i < len(w.Samples); i += n {
+		mono.Samples = append(mono.Samples, w.Samples[i])
+	}
+	return mono
+}
+
+func readWAV(path string) (*wavAudio, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decodeWAV(data)
+}
+
+func decodeWAV(data []byte) (*wavAudio, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("not a WAV file")
+	}
+
+	audio := &wavAudio{}
+	var channels, bitsPerSample uint16
+	haveFormat := false
+
+	// Walk the RIFF chunks looking for "fmt " and "data"
+	pos := 12
+	for pos+8 <= len(data) {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		body := pos + 8
+		end := body + size
+		// Streamed recordings may leave the data size unset or too large
+		if end > len(data) || size == 0 && id == "data" {
+			end = len(data)
+		}
+
+		switch id {
+		case "fmt ":
+			if end-body < 16 {
+				return nil, fmt.Errorf("malformed WAV format chunk")
+			}
+			format := binary.LittleEndian.Uint16(data[body : body+2])
+			channels = binary.LittleEndian.Uint16(data[body+2 : body+4])
+			audio.SampleRate = int(binary.LittleEndian.Uint32(data[body+4 : body+8]))
+			bitsPerSample = binary.LittleEndian.Uint16(data[body+14 : body+16])
+			if format != 1 || bitsPerSample != 16 {
+				return nil, fmt.Errorf("unsupported WAV encoding (format %d, %d bits)", format, bitsPerSample)
+			}
+			haveFormat = true
+		case "data":
+			if !haveFormat {
+				return nil, fmt.Errorf("WAV data before format chunk")
+			}
+			if channels == 0 {
+				return nil, fmt.Errorf("malformed WAV format chunk")
+			}
+			// Keep whole frames only; a streamed file may end mid-frame
+			frameSize := 2 * int(channels)
+			frames := (end - body) / frameSize
+			audio.Channels = int(channels)
+			audio.Samples = make([]int16, frames*int(channels))
+			for i := range audio.Samples {
+				off := body + i*2
+				audio.Samples[i] = int16(binary.LittleEndian.Uint16(data[off : off+2]))
+			}
+			return audio, nil
+		}
+
+		// Chunks are padded to an even size
+		pos = end + size%2
+	}
+
+	return nil, fmt.Errorf("WAV file has no data chunk")
+}
+
+func writeWAV(path string, audio *wavAudio) error {
+	var buf bytes.Buffer
+	if err := encodeWAV(&buf, audio); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+func encodeWAV(w io.Writer, audio *wavAudio) error {
+	channels := audio.channelCount()
+	dataSize := uint32(len(audio.Samples) * 2)
+	header := []interface{}{
+		[]byte("RIFF"), 36 + dataSize, []byte("WAVE"),
+		[]byte("fmt "), uint32(16), uint16(1), uint16(channels),
+		uint32(audio.SampleRate), uint32(audio.SampleRate * channels * 2), uint16(channels * 2), uint16(16),
+		[]byte("data"), dataSize,
+	}
+	for _, v := range header {
+		if err := binary.Write(w, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+	return binary.Write(w, binary.LittleEndian, audio.Samples)
+}
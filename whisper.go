@@ -1,56 +1,235 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
+	"strings"
 )
 
+// TranscriptionToken is a single decoded token with its probability
+type TranscriptionToken struct {
+	Text string  `json:"text"`
+	P    float64 `json:"p"`
+}
+
+// TranscriptSegment is a span of recognized speech. Start and End are
+// seconds relative to the beginning of the transcribed audio.
+type TranscriptSegment struct {
+	Start   float64              `json:"start"`
+	End     float64              `json:"end"`
+	Text    string               `json:"text"`
+	Speaker string               `json:"speaker,omitempty"` // Channel name in dual recordings
+	Tokens  []TranscriptionToken `json:"tokens,omitempty"`
+}
+
+// Line returns the segment's text, prefixed with its speaker if it has one
+func (s TranscriptSegment) Line() string {
+	text := strings.TrimSpace(s.Text)
+	if text == "" || s.Speaker == "" {
+		return text
+	}
+	return s.Speaker + ": " + text
+}
+
+// TranscriptionResult is the typed output of a transcription
+type TranscriptionResult struct {
+	Language string              `json:"language,omitempty"`
+	Segments []TranscriptSegment `json:"segments"`
+}
+
+// Text returns the segment texts, one segment per line
+func (r *TranscriptionResult) Text() string {
+	lines := make([]string, 0, len(r.Segments))
+	for _, seg := range r.Segments {
+		if text := strings.TrimSpace(seg.Text); text != "" {
+			lines = append(lines, text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LabeledText returns the segment lines as written to transcripts, with
+// speaker labels in dual recordings
+func (r *TranscriptionResult) LabeledText() string {
+	lines := make([]string, 0, len(r.Segments))
+	for _, seg := range r.Segments {
+		if line := seg.Line(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// LabeledWords returns word-level segments run together into lines, starting
+// a new line when the speaker changes
+func (r *TranscriptionResult) LabeledWords() string {
+	var lines []string
+	var words []string
+	speaker := ""
+	flush := func() {
+		if len(words) > 0 {
+			lines = append(lines, TranscriptSegment{Text: strings.Join(words, " "), Speaker: speaker}.Line())
+		}
+		words = nil
+	}
+	for _, seg := range r.Segments {
+		text := strings.TrimSpace(seg.Text)
+		if text == "" {
+			continue
+		}
+		if seg.Speaker != speaker {
+			flush()
+			speaker = seg.Speaker
+		}
+		words = append(words, text)
+	}
+	flush()
+	return strings.Join(lines, "\n")
+}
+
+// whisperJSON mirrors the file written by whisper-cli --output-json-full
+type whisperJSON struct {
+	Result struct {
+		Language string `json:"language"`
+	} `json:"result"`
+	Transcription []struct {
+		Offsets struct {
+			From int64 `json:"from"`
+			To   int64 `json:"to"`
+		} `json:"offsets"`
+		Text   string `json:"text"`
+		Tokens []struct {
+			Text string  `json:"text"`
+			P    float64 `json:"p"`
+		} `json:"tokens"`
+	} `json:"transcription"`
+}
+
+// TranscriptionBackend turns an audio file into timed segments. outputPath is
+// a base name the backend may use for intermediate files. prompt, when not
+// empty, is text that biases decoding toward its vocabulary and style.
+type TranscriptionBackend interface {
+	Transcribe(audioFile, outputPath, prompt string) (*TranscriptionResult, error)
+}
+
+// Backends selectable through the "backend" config key
+var supportedBackends = map[string]bool{
+	"cli":    true,
+	"server": true,
+	"openai": true,
+}
+
 type WhisperService struct {
-	config *Config
+	config  *Config
+	backend TranscriptionBackend
 }
 
 func NewWhisperService(config *Config) *WhisperService {
+	var backend TranscriptionBackend
+	switch config.Backend {
+	case "server":
+		backend = newWhisperServerBackend(config)
+	case "openai":
+		backend = newOpenAIBackend(config)
+	default:
+		backend = &whisperCLIBackend{config: config}
+	}
+
 	return &WhisperService{
-		config: config,
+		config:  config,
+		backend: backend,
 	}
 }
 
-func (w *WhisperService) ValidateModel() error {
-	if _, err := os.Stat(w.config.ModelPath); err != nil {
-		return fmt.Errorf("model file not found: %s", w.config.ModelPath)
+// Transcribe transcribes audioFile with the configured backend
+func (w *WhisperService) Transcribe(audioFile, outputPath, prompt string) (*TranscriptionResult, error) {
+	if _, err := os.Stat(audioFile); err != nil {
+		return nil, fmt.Errorf("audio file not accessible: %v", err)
 	}
-	return nil
+
+	fmt.Printf("Transcribing: %s\n", audioFile)
+	result, err := w.backend.Transcribe(audioFile, outputPath, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Printf("Transcribed %d segment(s): %s\n", len(result.Segments), audioFile)
+	return result, nil
 }
 
-func (w *WhisperService) Transcribe(audioFile, outputPath string) error {
-	if _, err := os.Stat(audioFile); err != nil {
-		return fmt.Errorf("audio file not accessible: %v", err)
+// whisperCLIBackend runs whisper-cli once per audio file
+type whisperCLIBackend struct {
+	config *Config
+}
+
+func (b *whisperCLIBackend) ValidateModel() error {
+	if _, err := os.Stat(b.config.ModelPath); err != nil {
+		return fmt.Errorf("model file not found: %s", b.config.ModelPath)
 	}
+	return nil
+}
 
-	if err := w.ValidateModel(); err != nil {
-		return err
+// Transcribe runs whisper-cli on audioFile and returns the decoded segments.
+// outputPath is the base name for whisper's intermediate JSON output, which
+// is removed once parsed.
+func (b *whisperCLIBackend) Transcribe(audioFile, outputPath, prompt string) (*TranscriptionResult, error) {
+	if err := b.ValidateModel(); err != nil {
+		return nil, err
 	}
 
-	outputFlag := "--output-" + w.config.OutputFormat
-	cmd := exec.Command(w.config.WhisperCmd,
+	cmd := exec.Command(b.config.WhisperCmd,
 		audioFile,
-		"-m", w.config.ModelPath,
-		"--language", w.config.Language,
-		outputFlag,
+		"-m", b.config.ModelPath,
+		"--language", b.config.Language,
+		"--output-json-full",
 		"-of", outputPath,
 	)
+	cmd.Args = append(cmd.Args, b.config.Whisper.cliArgs()...)
+	if prompt != "" {
+		cmd.Args = append(cmd.Args, "--prompt", prompt)
+	}
 
-	fmt.Printf("Transcribing: %s\n", audioFile)
 	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("transcription failed: %v", err)
+		return nil, fmt.Errorf("transcription failed: %v", err)
 	}
 
-	expectedFile := outputPath + "." + w.config.OutputFormat
-	if _, err := os.Stat(expectedFile); err != nil {
-		return fmt.Errorf("transcription output not found: %s", expectedFile)
+	expectedFile := outputPath + ".json"
+	data, err := os.ReadFile(expectedFile)
+	if err != nil {
+		return nil, fmt.Errorf("transcription output not found: %s", expectedFile)
 	}
+	defer os.Remove(expectedFile)
 
-	fmt.Printf("Transcription saved: %s\n", expectedFile)
-	return nil
+	result, err := parseWhisperJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse transcription output: %v", err)
+	}
+	return result, nil
+}
+
+func parseWhisperJSON(data []byte) (*TranscriptionResult, error) {
+	var raw whisperJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	result := &TranscriptionResult{Language: raw.Result.Language}
+	for _, item := range raw.Transcription {
+		seg := TranscriptSegment{
+			Start: float64(item.Offsets.From) / 1000,
+			End:   float64(item.Offsets.To) / 1000,
+			Text:  strings.TrimSpace(item.Text),
+		}
+		for _, tok := range item.Tokens {
+			// Skip special tokens such as [_BEG_] and [_TT_150]
+			if strings.HasPrefix(tok.Text, "[_") {
+				continue
+			}
+			seg.Tokens = append(seg.Tokens, TranscriptionToken{Text: tok.Text, P: tok.P})
+		}
+		result.Segments = append(result.Segments, seg)
+	}
+	return result, nil
 }
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// WhisperOptions are decoding parameters passed to the backend. Zero values
+// leave the backend's own default in place.
+type WhisperOptions struct {
+	BeamSize       int               `json:"beam_size,omitempty"`       // Beam search width; 0 uses greedy decoding
+	BestOf         int               `json:"best_of,omitempty"`         // Candidates sampled when decoding greedily
+	Temperature    float64           `json:"temperature,omitempty"`     // Initial sampling temperature
+	TemperatureInc float64           `json:"temperature_inc,omitempty"` // Temperature added on each fallback when decoding fails
+	NoFallback     bool              `json:"no_fallback,omitempty"`     // Never retry decoding at a higher temperature
+	Threads        int               `json:"threads,omitempty"`         // CPU threads whisper-cli uses
+	MaxLen         int               `json:"max_len,omitempty"`         // Maximum segment length in characters
+	WordTimestamps bool              `json:"word_timestamps,omitempty"` // One segment per word, each with its own timing
+	SplitOnWord    bool              `json:"split_on_word,omitempty"`   // Split segments on words rather than tokens when max_len applies
+	Translate      bool              `json:"translate,omitempty"`       // Translate the speech to English
+	ExtraArgs      []string          `json:"extra_args,omitempty"`      // Extra whisper-cli arguments, passed as given
+	ExtraFields    map[string]string `json:"extra_fields,omitempty"`    // Extra form fields sent to the server and openai backends
+}
+
+// validate checks the option values and that the backend supports them
+func (o WhisperOptions) validate(backend string) error {
+	switch {
+	case o.BeamSize < 0:
+		return fmt.Errorf("whisper.beam_size (%d) must not be negative", o.BeamSize)
+	case o.BestOf < 0:
+		return fmt.Errorf("whisper.best_of (%d) must not be negative", o.BestOf)
+	case o.Temperature < 0 || o.Temperature > 1:
+		return fmt.Errorf("whisper.temperature (%v) must be between 0 and 1", o.Temperature)
+	case o.TemperatureInc < 0 || o.TemperatureInc > 1:
+		return fmt.Errorf("whisper.temperature_inc (%v) must be between 0 and 1", o.TemperatureInc)
+	case o.NoFallback && o.TemperatureInc > 0:
+		return fmt.Errorf("whisper.no_fallback and whisper.temperature_inc cannot both be set")
+	case o.Threads < 0:
+		return fmt.Errorf("whisper.threads (%d) must not be negative", o.Threads)
+	case o.MaxLen < 0:
+		return fmt.Errorf("whisper.max_len (%d) must not be negative", o.MaxLen)
+	case o.WordTimestamps && o.MaxLen > 1:
+		return fmt.Errorf("whisper.word_timestamps splits segments per word and cannot be combined with whisper.max_len")
+	}
+
+	// Options each backend cannot pass on
+	var unsupported []string
+	switch backend {
+	case "server":
+		// The server's thread count is fixed when it starts
+		unsupported = o.setNames("threads", "extra_args")
+	case "openai":
+		unsupported = o.setNames("beam_size", "best_of", "temperature_inc", "no_fallback",
+			"threads", "max_len", "split_on_word", "extra_args")
+	default:
+		unsupported = o.setNames("extra_fields")
+	}
+	if len(unsupported) > 0 {
+		return fmt.Errorf("whisper.%s is not supported by the %s backend", unsupported[0], backend)
+	}
+	return nil
+}
+
+// setNames returns which of the named options are set
+func (o WhisperOptions) setNames(names ...string) []string {
+	set := map[string]bool{
+		"beam_size":       o.BeamSize > 0,
+		"best_of":         o.BestOf > 0,
+		"temperature_inc": o.TemperatureInc > 0,
+		"no_fallback":     o.NoFallback,
+		"threads":         o.Threads > 0,
+		"max_len":         o.MaxLen > 0,
+		"split_on_word":   o.SplitOnWord,
+		"extra_args":      len(o.ExtraArgs) > 0,
+		"extra_fields":    len(o.ExtraFields) > 0,
+	}
+	var found []string
+	for _, name := range names {
+		if set[name] {
+			found = append(found, name)
+		}
+	}
+	return found
+}
+
+// cliArgs maps the options to whisper-cli flags
+func (o WhisperOptions) cliArgs() []string {
+	var args []string
+	if o.Threads > 0 {
+		args = append(args, "-t", strconv.Itoa(o.Threads))
+	}
+	if o.BeamSize > 0 {
+		args = append(args, "-bs", strconv.Itoa(o.BeamSize))
+	}
+	if o.BestOf > 0 {
+		args = append(args, "-bo", strconv.Itoa(o.BestOf))
+	}
+	if o.Temperature > 0 {
+		args = append(args, "-tp", formatOption(o.Temperature))
+	}
+	if o.TemperatureInc > 0 {
+		args = append(args, "-tpi", formatOption(o.TemperatureInc))
+	}
+	if o.NoFallback {
+		args = append(args, "-nf")
+	}
+	if o.WordTimestamps {
+		args = append(args, "-ml", "1", "-sow")
+	} else {
+		if o.MaxLen > 0 {
+			args = append(args, "-ml", strconv.Itoa(o.MaxLen))
+		}
+		if o.SplitOnWord {
+			args = append(args, "-sow")
+		}
+	}
+	if o.Translate {
+		args = append(args, "-tr")
+	}
+	return append(args, o.ExtraArgs...)
+}
+
+// serverFields maps the options to whisper.cpp server /inference fields
+func (o WhisperOptions) serverFields(fields map[string]string) {
+	if o.BeamSize > 0 {
+		fields["beam_size"] = strconv.Itoa(o.BeamSize)
+	}
+	if o.BestOf > 0 {
+		fields["best_of"] = strconv.Itoa(o.BestOf)
+	}
+	if o.Temperature > 0 {
+		fields["temperature"] = formatOption(o.Temperature)
+	}
+	if o.TemperatureInc > 0 {
+		fields["temperature_inc"] = formatOption(o.TemperatureInc)
+	}
+	if o.NoFallback {
+		fields["temperature_inc"] = "0"
+	}
+	if o.WordTimestamps {
+		fields["max_len"] = "1"
+		fields["split_on_word"] = "true"
+	} else {
+		if o.MaxLen > 0 {
+			fields["max_len"] = strconv.Itoa(o.MaxLen)
+		}
+		if o.SplitOnWord {
+			fields["split_on_word"] = "true"
+		}
+	}
+	if o.Translate {
+		fields["translate"] = "true"
+	}
+	for name, value := range o.ExtraFields {
+		fields[name] = value
+	}
+}
+
+// openAIFields maps the options to OpenAI transcription request fields.
+// Translation uses its own endpoint; see openAIBackend.
+func (o WhisperOptions) openAIFields(fields map[string]string) {
+	if o.Temperature > 0 {
+		fields["temperature"] = formatOption(o.Temperature)
+	}
+	if o.WordTimestamps {
+		fields["timestamp_granularities[]"] = "word"
+	}
+	for name, value := range o.ExtraFields {
+		fields[name] = value
+	}
+}
+
+func formatOption(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
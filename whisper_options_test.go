@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWhisperOptionsCLIArgs(t *testing.T) {
+	opts := WhisperOptions{BeamSize: 5, BestOf: 3, Temperature: 0.2, NoFallback: true, Threads: 8,
+		WordTimestamps: true, Translate: true, ExtraArgs: []string{"--no-gpu"}}
+	want := []string{"-t", "8", "-bs", "5", "-bo", "3", "-tp", "0.2", "-nf", "-ml", "1", "-sow", "-tr", "--no-gpu"}
+	if got := opts.cliArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("cliArgs() = %v, want %v", got, want)
+	}
+}
+
+func TestWhisperOptionsValidate(t *testing.T) {
+	if err := (WhisperOptions{BeamSize: 5}).validate("openai"); err == nil {
+		t.Error("beam_size accepted for the openai backend")
+	}
+	if err := (WhisperOptions{Threads: 4}).validate("server"); err == nil {
+		t.Error("threads accepted for the server backend")
+	}
+	if err := (WhisperOptions{WordTimestamps: true, MaxLen: 40}).validate("cli"); err == nil {
+		t.Error("word_timestamps accepted with max_len")
+	}
+	if err := (WhisperOptions{BeamSize: 5, Threads: 4, ExtraArgs: []string{"-x"}}).validate("cli"); err != nil {
+		t.Errorf("valid cli options rejected: %v", err)
+	}
+}
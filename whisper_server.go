@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// whisperServerBackend posts audio to a long-running whisper.cpp server, which
+// keeps the model loaded between chunks
+type whisperServerBackend struct {
+	config *Config
+	client *http.Client
+}
+
+func newWhisperServerBackend(config *Config) *whisperServerBackend {
+	return &whisperServerBackend{
+		config: config,
+		client: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+func (b *whisperServerBackend) Transcribe(audioFile, outputPath, prompt string) (*TranscriptionResult, error) {
+	// The server takes ISO-639-1 codes and assumes English unless told to
+	// detect the language
+	language := languageCode(b.config.Language)
+	if language == "" {
+		language = "auto"
+	}
+
+	fields := map[string]string{
+		"response_format": "verbose_json",
+		"language":        language,
+		"prompt":          prompt,
+	}
+	b.config.Whisper.serverFields(fields)
+
+	body, contentType, err := newMultipartUpload(audioFile, fields)
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimRight(b.config.ServerURL, "/") + "/inference"
+	resp, err := b.client.Post(url, contentType, body)
+	if err != nil {
+		return nil, fmt.Errorf("whisper server request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read whisper server response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("whisper server returned HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+
+	result, err := parseVerboseJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse whisper server response: %v", err)
+	}
+	return result, nil
+}
+
+// newMultipartUpload builds a multipart form with the audio as "file" plus
+// the given fields
+func newMultipartUpload(audioFile string, fields map[string]string) (*bytes.Buffer, string, error) {
+	f, err := os.Open(audioFile)
+	if err != nil {
+		return nil, "", fmt.Errorf("audio file not accessible: %v", err)
+	}
+	defer f.Close()
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+
+	part, err := mw.CreateFormFile("file", filepath.Base(audioFile))
+	if err != nil {
+		return nil, "", err
+	}
+	if _, err := io.Copy(part, f); err != nil {
+		return nil, "", fmt.Errorf("failed to read audio file: %v", err)
+	}
+
+	for name, value := range fields {
+		if value == "" {
+			continue
+		}
+		if err := mw.WriteField(name, value); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := mw.Close(); err != nil {
+		return nil, "", err
+	}
+	return body, mw.FormDataContentType(), nil
+}
+
+// verboseJSON mirrors the verbose_json response format shared by the
+// whisper.cpp server and OpenAI-style transcription APIs
+type verboseJSON struct {
+	Language string     `json:"language"`
+	Text     string     `json:"text"`
+	Words    []struct { // Word-level timings, returned for timestamp_granularities[]=word
+		Word  string  `json:"word"`
+		Start float64 `json:"start"`
+		End   float64 `json:"end"`
+	} `json:"words"`
+	Segments []struct {
+		Start      float64  `json:"start"`
+		End        float64  `json:"end"`
+		Text       string   `json:"text"`
+		AvgLogprob *float64 `json:"avg_logprob"`
+		Words      []struct {
+			Word        string  `json:"word"`
+			Probability float64 `json:"probability"`
+		} `json:"words"`
+	} `json:"segments"`
+}
+
+func parseVerboseJSON(data []byte) (*TranscriptionResult, error) {
+	var raw verboseJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	result := &TranscriptionResult{Language: raw.Language}
+	for _, item := range raw.Segments {
+		seg := TranscriptSegment{
+			Start: item.Start,
+			End:   item.End,
+			Text:  strings.TrimSpace(item.Text),
+		}
+		for _, word := range item.Words {
+			seg.Tokens = append(seg.Tokens, TranscriptionToken{Text: word.Word, P: word.Probability})
+		}
+		// Without per-word probabilities, carry the segment's average as a
+		// single token spanning the whole text
+		if len(seg.Tokens) == 0 && item.AvgLogprob != nil {
+			seg.Tokens = []TranscriptionToken{{Text: seg.Text, P: math.Exp(*item.AvgLogprob)}}
+		}
+		result.Segments = append(result.Segments, seg)
+	}
+
+	// Word-level responses carry timings per word instead of segments
+	if len(result.Segments) == 0 {
+		for _, word := range raw.Words {
+			if text := strings.TrimSpace(word.Word); text != "" {
+				result.Segments = append(result.Segments, TranscriptSegment{Start: word.Start, End: word.End, Text: text})
+			}
+		}
+	}
+
+	// Responses without segments still carry the full text
+	if len(result.Segments) == 0 && strings.TrimSpace(raw.Text) != "" {
+		result.Segments = append(result.Segments, TranscriptSegment{Text: strings.TrimSpace(raw.Text)})
+	}
+	return result, nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeTestAudio writes a small stand-in audio file for upload tests
+func writeTestAudio(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "chunk_00001.wav")
+	if err := os.WriteFile(path, []byte("RIFF test audio"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// uploadRecorder is an httptest handler that records the multipart upload it
+// receives and answers with a fixed status and body
+type uploadRecorder struct {
+	status int
+	body   string
+
+	path   string
+	header http.Header
+	fields map[string]string
+	file   string
+}
+
+func (u *uploadRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.path = r.URL.Path
+	u.header = r.Header.Clone()
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	u.fields = make(map[string]string)
+	for name, values := range r.MultipartForm.Value {
+		u.fields[name] = values[0]
+	}
+	if files := r.MultipartForm.File["file"]; len(files) > 0 {
+		u.file = files[0].Filename
+	}
+	w.WriteHeader(u.status)
+	w.Write([]byte(u.body))
+}
+
+func TestWhisperServerBackendTranscribe(t *testing.T) {
+	upload := &uploadRecorder{
+		status: http.StatusOK,
+		body: `{"language":"en","segments":[
+			{"start":0.0,"end":2.5,"text":" Hello there.","avg_logprob":-0.1},
+			{"start":2.5,"end":4.0,"text":" General Kenobi."}
+		]}`,
+	}
+	srv := httptest.NewServer(upload)
+	defer srv.Close()
+
+	config := &Config{
+		ServerURL: srv.URL + "/",
+		Language:  "Spanish",
+		Whisper:   WhisperOptions{BeamSize: 5, Translate: true},
+	}
+	result, err := newWhisperServerBackend(config).Transcribe(writeTestAudio(t), "", "Glossary: Kenobi.")
+	if err != nil {
+		t.Fatalf("Transcribe: %v", err)
+	}
+
+	if upload.path != "/inference" {
+		t.Errorf("posted to %q, want /inference", upload.path)
+	}
+	if upload.file != "chunk_00001.wav" {
+		t.Errorf("uploaded file %q, want chunk_00001.wav", upload.file)
+	}
+	wantFields := map[string]string{
+		"response_format": "verbose_json",
+		"language":        "es",
+		"prompt":          "Glossary: Kenobi.",
+		"beam_size":       "5",
+		"translate":       "true",
+	}
+	if !reflect.DeepEqual(upload.fields, wantFields) {
+		t.Errorf("fields = %v, want %v", upload.fields, wantFields)
+	}
+
+	if len(result.Segments) != 2 || result.Segments[0].Text != "Hello there." || result.Segments[1].End != 4.0 {
+		t.Errorf("unexpected segments: %+v", result.Segments)
+	}
+}
+
+func TestWhisperServerBackendLanguage(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"English", "en"},
+		{"de", "de"},
+		{"auto", "auto"},
+		{"", "auto"},
+	}
+	for _, tt := range tests {
+		upload := &uploadRecorder{status: http.StatusOK, body: `{"text":""}`}
+		srv := httptest.NewServer(upload)
+		config := &Config{ServerURL: srv.URL, Language: tt.language}
+		_, err := newWhisperServerBackend(config).Transcribe(writeTestAudio(t), "", "")
+		srv.Close()
+		if err != nil {
+			t.Fatalf("language %q: %v", tt.language, err)
+		}
+		if got := upload.fields["language"]; got != tt.want {
+			t.Errorf("language %q sent as %q, want %q", tt.language, got, tt.want)
+		}
+	}
+}
+
+func TestWhisperServerBackendHTTPError(t *testing.T) {
+	upload := &uploadRecorder{status: http.StatusInternalServerError, body: "failed to load audio\n"}
+	srv := httptest.NewServer(upload)
+	defer srv.Close()
+
+	_, err := newWhisperServerBackend(&Config{ServerURL: srv.URL}).Transcribe(writeTestAudio(t), "", "")
+	if err == nil {
+		t.Fatal("expected an error for HTTP 500")
+	}
+	if !strings.Contains(err.Error(), "HTTP 500") || !strings.Contains(err.Error(), "failed to load audio") {
+		t.Errorf("error %q should carry the status and body", err)
+	}
+}
+
+func TestParseVerboseJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []TranscriptSegment
+	}{
+		{
+			name: "segments with word probabilities",
+			data: `{"segments":[{"start":1.5,"end":3,"text":" Hi all ","words":[{"word":"Hi","probability":0.9},{"word":" all","probability":0.8}]}]}`,
+			want: []TranscriptSegment{{Start: 1.5, End: 3, Text: "Hi all", Tokens: []TranscriptionToken{{Text: "Hi", P: 0.9}, {Text: " all", P: 0.8}}}},
+		},
+		{
+			name: "average log probability becomes one token",
+			data: `{"segments":[{"start":0,"end":2,"text":"Hi all","avg_logprob":0}]}`,
+			want: []TranscriptSegment{{Start: 0, End: 2, Text: "Hi all", Tokens: []TranscriptionToken{{Text: "Hi all", P: 1}}}},
+		},
+		{
+			name: "word timings without segments",
+			data: `{"text":"Hi all","words":[{"word":"Hi","start":0,"end":0.4},{"word":" ","start":0.4,"end":0.5},{"word":"all","start":0.5,"end":0.9}]}`,
+			want: []TranscriptSegment{{Start: 0, End: 0.4, Text: "Hi"}, {Start: 0.5, End: 0.9, Text: "all"}},
+		},
+		{
+			name: "text only",
+			data: `{"text":" Hi all "}`,
+			want: []TranscriptSegment{{Text: "Hi all"}},
+		},
+		{
+			name: "empty",
+			data: `{"text":""}`,
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := parseVerboseJSON([]byte(tt.data))
+			if err != nil {
+				t.Fatalf("parseVerboseJSON: %v", err)
+			}
+			if !reflect.DeepEqual(result.Segments, tt.want) {
+				t.Errorf("segments = %+v, want %+v", result.Segments, tt.want)
+			}
+		})
+	}
+
+	result, err := parseVerboseJSON([]byte(`{"segments":[{"text":"x","avg_logprob":-0.6931471805599453}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := result.Segments[0].Tokens[0].P; math.Abs(p-0.5) > 1e-9 {
+		t.Errorf("avg_logprob of ln(0.5) gave probability %v, want 0.5", p)
+	}
+
+	if _, err := parseVerboseJSON([]byte("<html>")); err == nil {
+		t.Error("expected an error for a non-JSON body")
+	}
+}
+
+func TestParseWhisperJSON(t *testing.T) {
+	data := `{
+		"result": {"language": "en"},
+		"transcription": [
+			{
+				"offsets": {"from": 0, "to": 2340},
+				"text": " Hello world.",
+				"tokens": [
+					{"text": "[_BEG_]", "p": 0.99},
+					{"text": " Hello", "p": 0.95},
+					{"text": " world", "p": 0.9},
+					{"text": ".", "p": 0.8},
+					{"text": "[_TT_117]", "p": 0.5}
+				]
+			},
+			{"offsets": {"from": 2340, "to": 61005}, "text": " Next part"}
+		]
+	}`
+	result, err := parseWhisperJSON([]byte(data))
+	if err != nil {
+		t.Fatalf("parseWhisperJSON: %v", err)
+	}
+
+	want := &TranscriptionResult{
+		Language: "en",
+		Segments: []TranscriptSegment{
+			{Start: 0, End: 2.34, Text: "Hello world.", Tokens: []TranscriptionToken{{Text: " Hello", P: 0.95}, {Text: " world", P: 0.9}, {Text: ".", P: 0.8}}},
+			{Start: 2.34, End: 61.005, Text: "Next part"},
+		},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("got %+v, want %+v", result, want)
+	}
+
+	if _, err := parseWhisperJSON([]byte(`{"transcription": [`)); err == nil {
+		t.Error("expected an error for truncated output")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// outputPathBackend records where the backend was asked to write its output
+type outputPathBackend struct {
+	outputPath string
+}
+
+func (b *outputPathBackend) Transcribe(audioFile, outputPath, prompt string) (*TranscriptionResult, error) {
+	b.outputPath = outputPath
+	return &TranscriptionResult{Segments: []TranscriptSegment{{Text: "hello"}}}, nil
+}
+
+func TestIntermediateOutputStaysInTempDir(t *testing.T) {
+	tempDir := t.TempDir()
+	outputDir := t.TempDir()
+
+	chunkPath := filepath.Join(tempDir, "chunk_20250101_120000_3.wav")
+	if err := os.WriteFile(chunkPath, []byte("not a wav"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	backend := &outputPathBackend{}
+	tr := &Transcriber{config: Config{TempDir: tempDir, HallucinationFilter: "off"}}
+	tr.whisperService = &WhisperService{config: &tr.config, backend: backend}
+
+	session := newSession("20250101_120000", filepath.Join(outputDir, "run_20250101_120000"))
+	if _, err := tr.transcribeChunkAudio(session, AudioChunk{Num: 3, Path: chunkPath}); err != nil {
+		t.Fatalf("transcribeChunkAudio: %v", err)
+	}
+
+	want := filepath.Join(tempDir, "chunk_20250101_120000_3")
+	if backend.outputPath != want {
+		t.Errorf("backend output path = %q, want %q", backend.outputPath, want)
+	}
+}
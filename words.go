@@ -0,0 +1,171 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// mapLines runs fn over a chunk's segments joined into one line per speaker,
+// so stages that match text across words see phrases and numbers that
+// whisper split over several segments, or into one segment per word with
+// whisper.word_timestamps. fn returns the rewritten line, or false to drop
+// it. The result is mapped back onto the segments with remapWords.
+func mapLines(segments []TranscriptSegment, fn func(line TranscriptSegment) (TranscriptSegment, bool)) []TranscriptSegment {
+	if len(segments) == 0 {
+		return segments
+	}
+
+	// Dual recordings interleave the segments of each channel
+	var speakers []string
+	bySpeaker := make(map[string][]TranscriptSegment)
+	for _, seg := range segments {
+		if _, ok := bySpeaker[seg.Speaker]; !ok {
+			speakers = append(speakers, seg.Speaker)
+		}
+		bySpeaker[seg.Speaker] = append(bySpeaker[seg.Speaker], seg)
+	}
+
+	var mapped []TranscriptSegment
+	for _, speaker := range speakers {
+		group := bySpeaker[speaker]
+		line := TranscriptSegment{Start: group[0].Start, End: group[len(group)-1].End, Speaker: speaker}
+		var texts []string
+		for _, seg := range group {
+			if text := strings.TrimSpace(seg.Text); text != "" {
+				texts = append(texts, text)
+			}
+			line.Tokens = append(line.Tokens, seg.Tokens...)
+		}
+		line.Text = strings.Join(texts, " ")
+
+		result, ok := fn(line)
+		if !ok {
+			continue
+		}
+		mapped = append(mapped, remapWords(group, result.Text)...)
+	}
+
+	if len(speakers) > 1 {
+		sort.SliceStable(mapped, func(i, j int) bool { return mapped[i].Start < mapped[j].Start })
+	}
+	return mapped
+}
+
+// remapWords distributes the words of a rewritten line over the segments it
+// was joined from, matching them to the original words. Segments whose words
+// are unchanged are kept as they were. A run of words replaced by as many
+// words keeps each word in its segment; any other replacement, such as a
+// phone number turned into one placeholder, goes to the segment the run
+// started in. Segments left without words are dropped, and their time goes to
+// the segment that took their words.
+func remapWords(segments []TranscriptSegment, text string) []TranscriptSegment {
+	var old []string
+	var owner []int // Segment each old word came from
+	for i, seg := range segments {
+		for _, word := range strings.Fields(seg.Text) {
+			old = append(old, word)
+			owner = append(owner, i)
+		}
+	}
+	fields := strings.Fields(text)
+
+	assigned := make([][]string, len(segments))
+	changed := make([]bool, len(segments))
+	into := make([]int, len(segments)) // Segment that took a segment's words
+	for i := range into {
+		into[i] = -1
+	}
+
+	replace := func(oldStart, oldEnd, newStart, newEnd int) {
+		from, to := owner[oldStart:oldEnd], fields[newStart:newEnd]
+		for _, o := range from {
+			changed[o] = true
+		}
+		switch {
+		case len(from) == 0 && len(to) > 0:
+			// Inserted: joins the previous word's segment, or the first one
+			o := 0
+			if oldStart > 0 {
+				o = owner[oldStart-1]
+			}
+			changed[o] = true
+			assigned[o] = append(assigned[o], to...)
+		case len(from) == len(to):
+			for k := range from {
+				assigned[from[k]] = append(assigned[from[k]], to[k])
+			}
+		case len(to) > 0:
+			assigned[from[0]] = append(assigned[from[0]], to...)
+			for _, o := range from[1:] {
+				if o != from[0] {
+					into[o] = from[0]
+				}
+			}
+		}
+	}
+
+	i, j := 0, 0
+	oldStart, newStart := 0, 0
+	lcs := commonWords(old, fields)
+	for i < len(old) && j < len(fields) {
+		switch {
+		case old[i] == fields[j] && lcs[i][j] == lcs[i+1][j+1]+1:
+			replace(oldStart, i, newStart, j)
+			assigned[owner[i]] = append(assigned[owner[i]], old[i])
+			i, j = i+1, j+1
+			oldStart, newStart = i, j
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	replace(oldStart, len(old), newStart, len(fields))
+
+	end := make([]float64, len(segments))
+	for i, seg := range segments {
+		end[i] = seg.End
+	}
+	for i, seg := range segments {
+		if changed[i] && len(assigned[i]) == 0 && into[i] >= 0 && seg.End > end[into[i]] {
+			end[into[i]] = seg.End
+		}
+	}
+
+	var out []TranscriptSegment
+	for i, seg := range segments {
+		if !changed[i] {
+			if strings.TrimSpace(seg.Text) != "" {
+				out = append(out, seg)
+			}
+			continue
+		}
+		if len(assigned[i]) == 0 {
+			continue
+		}
+		seg.Text = strings.Join(assigned[i], " ")
+		seg.End = end[i]
+		seg.Tokens = nil // Would still spell out the original
+		out = append(out, seg)
+	}
+	return out
+}
+
+// commonWords returns the longest common subsequence table of a and b:
+// entry [i][j] is the length for a[i:] and b[j:]
+func commonWords(a, b []string) [][]int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	return lcs
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// words builds one-word segments, each a second long from start
+func words(start float64, texts ...string) []TranscriptSegment {
+	segments := make([]TranscriptSegment, len(texts))
+	for i, text := range texts {
+		segments[i] = TranscriptSegment{Start: start + float64(i), End: start + float64(i) + 1, Text: text}
+	}
+	return segments
+}
+
+func TestRemapWords(t *testing.T) {
+	callMeAt := words(0, "call", "me", "at")
+	callNumber := words(0, "call", "555-123", "4567")
+	tests := []struct {
+		name  string
+		input []TranscriptSegment
+		text  string
+		want  []TranscriptSegment
+	}{
+		{
+			name:  "unchanged",
+			input: callMeAt,
+			text:  "call me at",
+			want:  words(0, "call", "me", "at"),
+		},
+		{
+			name:  "same number of words keep their timings",
+			input: callMeAt,
+			text:  "Call me at",
+			want:  words(0, "Call", "me", "at"),
+		},
+		{
+			name:  "several words become one",
+			input: callNumber,
+			text:  "call [PHONE_1]",
+			want:  []TranscriptSegment{{Start: 0, End: 1, Text: "call"}, {Start: 1, End: 3, Text: "[PHONE_1]"}},
+		},
+		{
+			name:  "words removed",
+			input: callNumber,
+			text:  "call",
+			want:  words(0, "call"),
+		},
+		{
+			name:  "word inserted at the start",
+			input: callMeAt,
+			text:  "so call me at",
+			want:  []TranscriptSegment{{Start: 0, End: 1, Text: "so call"}, {Start: 1, End: 2, Text: "me"}, {Start: 2, End: 3, Text: "at"}},
+		},
+		{
+			name: "sentence segments",
+			input: []TranscriptSegment{
+				{Start: 0, End: 2, Text: " Call me at 555", Tokens: []TranscriptionToken{{Text: " Call"}}},
+				{Start: 2, End: 4, Text: " 123 4567."},
+				{Start: 4, End: 5, Text: " Thanks."},
+			},
+			text: "Call me at [PHONE_1]. Thanks.",
+			want: []TranscriptSegment{{Start: 0, End: 4, Text: "Call me at [PHONE_1]."}, {Start: 4, End: 5, Text: " Thanks."}},
+		},
+		{
+			name:  "everything replaced",
+			input: callMeAt,
+			text:  "",
+			want:  nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := remapWords(tt.input, tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapLinesBySpeaker(t *testing.T) {
+	segments := []TranscriptSegment{
+		{Start: 0, End: 1, Text: "hi", Speaker: "Me"},
+		{Start: 0.5, End: 1.5, Text: "hello", Speaker: "Them"},
+		{Start: 1, End: 2, Text: "there", Speaker: "Me"},
+	}
+	var lines []string
+	got := mapLines(segments, func(line TranscriptSegment) (TranscriptSegment, bool) {
+		lines = append(lines, line.Speaker+": "+line.Text)
+		return line, line.Speaker == "Me"
+	})
+
+	if want := []string{"Me: hi there", "Them: hello"}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("lines = %q, want %q", lines, want)
+	}
+	if want := []TranscriptSegment{segments[0], segments[2]}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestLabeledWords(t *testing.T) {
+	result := &TranscriptionResult{Segments: []TranscriptSegment{
+		{Text: "can", Speaker: "Me"}, {Text: "you", Speaker: "Me"}, {Text: "hear?", Speaker: "Me"},
+		{Text: "yes", Speaker: "Them"}, {Text: "thanks", Speaker: "Me"},
+	}}
+	want := "Me: can you hear?\nThem: yes\nMe: thanks"
+	if got := result.LabeledWords(); got != want {
+		t.Errorf("LabeledWords() = %q, want %q", got, want)
+	}
+}
+
+// wordBackend returns fixed word-level segments, as whisper does with
+// word_timestamps
+type wordBackend struct {
+	words []TranscriptSegment
+}
+
+func (b *wordBackend) Transcribe(audioFile, outputPath, prompt string) (*TranscriptionResult, error) {
+	return &TranscriptionResult{Segments: append([]TranscriptSegment(nil), b.words...)}, nil
+}
+
+// newWordTranscriber loads a config that sets only word_timestamps plus
+// extra, and answers every chunk with words
+func newWordTranscriber(t *testing.T, extra string, words []TranscriptSegment) *Transcriber {
+	t.Helper()
+	configDir := t.TempDir()
+	config := fmt.Sprintf(`{"temp_dir": %q, "whisper": {"word_timestamps": true}%s}`, t.TempDir(), extra)
+	if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tr, err := NewTranscriber(configDir)
+	if err != nil {
+		t.Fatalf("NewTranscriber: %v", err)
+	}
+	tr.whisperService = &WhisperService{config: &tr.config, backend: &wordBackend{words: words}}
+	return tr
+}
+
+func TestWordTimestampsWithDefaultFilters(t *testing.T) {
+	tr := newWordTranscriber(t, "", nil)
+	if !tr.config.Whisper.WordTimestamps || tr.config.HallucinationFilter != "on" {
+		t.Fatalf("config = %+v, want word_timestamps with the hallucination filter on", tr.config)
+	}
+}
+
+func TestWordTimestampsTranscript(t *testing.T) {
+	spoken := words(0, "so", "so", "so", "so", "please", "call", "555", "123", "4567", "gonna", "be", "late.")
+	tr := newWordTranscriber(t, `, "redact_pii": true, "redact_types": ["phone"],
+		"text_filters": [{"type": "dictionary", "words": {"gonna": "going to"}}]`, spoken)
+
+	dir := t.TempDir()
+	chunkPath := filepath.Join(dir, "chunk_1.wav")
+	if err := os.WriteFile(chunkPath, []byte("not a wav"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	session := newSession("20250101_120000", filepath.Join(dir, "run_20250101_120000"))
+	chunk := AudioChunk{Num: 1, Path: chunkPath, Start: 0, End: 12}
+
+	result, err := tr.transcribeChunkAudio(session, chunk)
+	if err != nil {
+		t.Fatalf("transcribeChunkAudio: %v", err)
+	}
+	if err := tr.commitChunk(session, chunk, result, false); err != nil {
+		t.Fatalf("commitChunk: %v", err)
+	}
+
+	data, err := os.ReadFile(session.MainFile("txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "[0:00 - 0:12]\nso please call [PHONE_1] going to be late.\n"
+	if string(data) != want {
+		t.Errorf("transcript = %q, want %q", data, want)
+	}
+
+	// Filtering kept the timing of every word it left alone
+	var phone *TranscriptSegment
+	for i := range result.Segments {
+		if result.Segments[i].Text == "555" {
+			phone = &result.Segments[i]
+		}
+	}
+	if phone == nil || phone.Start != 6 || phone.End != 7 {
+		t.Errorf("filtered words should keep their timings: %+v", result.Segments)
+	}
+}
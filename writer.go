@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// Output formats supported for transcripts
+var supportedOutputFormats = map[string]bool{
+	"txt":  true,
+	"json": true,
+	"srt":  true,
+	"vtt":  true,
+}
+
+// TranscriptWriter appends a chunk's segments, already shifted onto the
+// session timeline, to a transcript file in one output format. The file on
+// disk is complete and valid after every append.
+type TranscriptWriter interface {
+	Append(chunk AudioChunk, segments []TranscriptSegment) error
+}
+
+// newTranscriptWriter returns the writer for format. words marks word-level
+// segments, which plain text runs together into lines.
+func newTranscriptWriter(format, path string, words bool) (TranscriptWriter, error) {
+	switch format {
+	case "txt":
+		return &textWriter{path: path, words: words}, nil
+	case "json":
+		return newJSONWriter(path)
+	case "srt":
+		return newSubtitleWriter(path, false)
+	case "vtt":
+		return newSubtitleWriter(path, true)
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", format)
+	}
+}
+
+// textWriter writes one "[start - end]" block per chunk
+type textWriter struct {
+	path  string
+	words bool // Segments are single words
+}
+
+func (w *textWriter) Append(chunk AudioChunk, segments []TranscriptSegment) error {
+	if len(segments) == 0 {
+		return nil
+	}
+
+	// Open main file for appending
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	// Use the real speech offsets, kept within the chunk's own span
+	startSeconds := math.Max(segments[0].Start, chunk.Start)
+	endSeconds := math.Max(segments[len(segments)-1].End, startSeconds)
+
+	var buf bytes.Buffer
+	// Add chunk separator and content
+	if info, err := f.Stat(); err == nil && info.Size() > 0 {
+		buf.WriteString("\n\n")
+	}
+	fmt.Fprintf(&buf, "[%s - %s]\n", formatTimestamp(int(startSeconds)), formatTimestamp(int(math.Ceil(endSeconds))))
+	result := &TranscriptionResult{Segments: segments}
+	if w.words {
+		buf.WriteString(result.LabeledWords() + "\n")
+	} else {
+		buf.WriteString(result.LabeledText() + "\n")
+	}
+
+	_, err = f.Write(buf.Bytes())
+	return err
+}
+
+// jsonWriter keeps the whole transcript as a single JSON document and
+// rewrites it atomically on every append
+type jsonWriter struct {
+	path       string
+	transcript TranscriptionResult
+}
+
+func newJSONWriter(path string) (*jsonWriter, error) {
+	w := &jsonWriter{path: path}
+
+	// Continue an existing transcript
+	data, err := os.ReadFile(path)
+	if err == nil && len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, &w.transcript); err != nil {
+			return nil, fmt.Errorf("existing transcript %s is not valid JSON: %v", path, err)
+		}
+	} else if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *jsonWriter) Append(chunk AudioChunk, segments []TranscriptSegment) error {
+	w.transcript.Segments = append(w.transcript.Segments, segments...)
+
+	data, err := json.MarshalIndent(w.transcript, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := w.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, w.path)
+}
+
+// subtitleWriter writes SRT or WebVTT cues numbered across the whole
+// session rather than restarting in every chunk
+type subtitleWriter struct {
+	path    string
+	vtt     bool
+	nextCue int
+}
+
+func newSubtitleWriter(path string, vtt bool) (*subtitleWriter, error) {
+	w := &subtitleWriter{path: path, vtt: vtt, nextCue: 1}
+
+	// Continue numbering after the cues already in the file
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return w, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), " --> ") {
+			w.nextCue++
+		}
+	}
+	return w, scanner.Err()
+}
+
+func (w *subtitleWriter) Append(chunk AudioChunk, segments []TranscriptSegment) error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if w.vtt && info.Size() == 0 {
+		buf.WriteString("WEBVTT\n\n")
+	}
+
+	cue := w.nextCue
+	for _, seg := range segments {
+		text := subtitleText(seg.Line())
+		if text == "" {
+			continue
+		}
+		end := math.Max(seg.End, seg.Start+0.001)
+		fmt.Fprintf(&buf, "%d\n%s --> %s\n%s\n\n", cue,
+			w.formatCueTime(seg.Start), w.formatCueTime(end), text)
+		cue++
+	}
+
+	// Write the chunk's cues in one call so readers never see half a cue
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	w.nextCue = cue
+	return nil
+}
+
+// formatCueTime formats seconds as HH:MM:SS,mmm (SRT) or HH:MM:SS.mmm (VTT)
+func (w *subtitleWriter) formatCueTime(seconds float64) string {
+	ms := int64(math.Round(seconds * 1000))
+	if ms < 0 {
+		ms = 0
+	}
+	sep := ","
+	if w.vtt {
+		sep = "."
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
+
+// subtitleText makes segment text safe for a cue payload, which must not
+// contain blank lines or the cue timing arrow
+func subtitleText(text string) string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, strings.ReplaceAll(line, "-->", "->"))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// formatTimestamp formats seconds as MM:SS or HH:MM:SS
+func formatTimestamp(seconds int) string {
+	hours := seconds / 3600
+	minutes := (seconds % 3600) / 60
+	secs := seconds % 60
+
+	if hours > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", hours, minutes, secs)
+	}
+	return fmt.Sprintf("%d:%02d", minutes, secs)
+}
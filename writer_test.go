@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func appendChunk(t *testing.T, w TranscriptWriter, chunk AudioChunk, segments ...TranscriptSegment) {
+	t.Helper()
+	if err := w.Append(chunk, segments); err != nil {
+		t.Fatalf("Append chunk %d: %v", chunk.Num, err)
+	}
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSubtitleCueNumbering(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "srt",
+			want: "1\n00:00:01,000 --> 00:00:02,500\nhello\n\n" +
+				"2\n00:00:02,500 --> 00:00:04,000\nthere\n\n" +
+				"3\n00:00:31,000 --> 00:00:33,000\nsecond chunk\n\n" +
+				"4\n00:01:01,000 --> 00:01:02,000\nThem: after reopening\n\n",
+		},
+		{
+			format: "vtt",
+			want: "WEBVTT\n\n" +
+				"1\n00:00:01.000 --> 00:00:02.500\nhello\n\n" +
+				"2\n00:00:02.500 --> 00:00:04.000\nthere\n\n" +
+				"3\n00:00:31.000 --> 00:00:33.000\nsecond chunk\n\n" +
+				"4\n00:01:01.000 --> 00:01:02.000\nThem: after reopening\n\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "run."+tt.format)
+			w, err := newTranscriptWriter(tt.format, path, false)
+			if err != nil {
+				t.Fatalf("newTranscriptWriter: %v", err)
+			}
+			appendChunk(t, w, AudioChunk{Num: 1, Start: 0, End: 30},
+				TranscriptSegment{Start: 1, End: 2.5, Text: " hello"},
+				TranscriptSegment{Start: 2.5, End: 4, Text: " there"})
+			appendChunk(t, w, AudioChunk{Num: 2, Start: 30, End: 60},
+				TranscriptSegment{Start: 30, End: 31, Text: " "},
+				TranscriptSegment{Start: 31, End: 33, Text: "second chunk"})
+
+			// A resumed session opens a new writer on the same file
+			w, err = newTranscriptWriter(tt.format, path, false)
+			if err != nil {
+				t.Fatalf("reopening: %v", err)
+			}
+			appendChunk(t, w, AudioChunk{Num: 3, Start: 60, End: 90},
+				TranscriptSegment{Start: 61, End: 62, Text: "after reopening", Speaker: "Them"})
+
+			if got := readFile(t, path); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONWriterRewritesOnAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.json")
+	first := TranscriptSegment{Start: 0, End: 2, Text: "first"}
+	second := TranscriptSegment{Start: 30, End: 31, Text: "second"}
+	third := TranscriptSegment{Start: 60, End: 62, Text: "third"}
+
+	read := func() []TranscriptSegment {
+		t.Helper()
+		var transcript TranscriptionResult
+		if err := json.Unmarshal([]byte(readFile(t, path)), &transcript); err != nil {
+			t.Fatalf("transcript is not valid JSON: %v", err)
+		}
+		return transcript.Segments
+	}
+
+	w, err := newTranscriptWriter("json", path, false)
+	if err != nil {
+		t.Fatalf("newTranscriptWriter: %v", err)
+	}
+	appendChunk(t, w, AudioChunk{Num: 1}, first)
+	if got := read(); !reflect.DeepEqual(got, []TranscriptSegment{first}) {
+		t.Errorf("after one chunk got %+v", got)
+	}
+	appendChunk(t, w, AudioChunk{Num: 2}, second)
+
+	w, err = newTranscriptWriter("json", path, false)
+	if err != nil {
+		t.Fatalf("reopening: %v", err)
+	}
+	appendChunk(t, w, AudioChunk{Num: 3}, third)
+	if got, want := read(), []TranscriptSegment{first, second, third}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("[1, 2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTranscriptWriter("json", path, false); err == nil || !strings.Contains(err.Error(), "not valid JSON") {
+		t.Errorf("corrupt transcript: err = %v", err)
+	}
+}
+
+func TestOutputFormatConfig(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string // Empty when the format is rejected
+	}{
+		{format: "SRT", want: "srt"},
+		{format: "vtt", want: "vtt"},
+		{format: "lrc"},
+		{format: "csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			configDir := t.TempDir()
+			config := fmt.Sprintf(`{"temp_dir": %q, "output_format": %q}`, t.TempDir(), tt.format)
+			if err := os.WriteFile(filepath.Join(configDir, "config.json"), []byte(config), 0644); err != nil {
+				t.Fatal(err)
+			}
+			tr, err := NewTranscriber(configDir)
+			if tt.want == "" {
+				if err == nil || !strings.Contains(err.Error(), "unsupported output_format") {
+					t.Errorf("err = %v, want unsupported output_format", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewTranscriber: %v", err)
+			}
+			if tr.config.OutputFormat != tt.want {
+				t.Errorf("output_format = %q, want %q", tr.config.OutputFormat, tt.want)
+			}
+		})
+	}
+}